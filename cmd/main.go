@@ -50,8 +50,7 @@ func main() {
 			Error("Failed to init postgres DB")
 		return
 	}
-	scansDao := dao.NewScansDao(logrus.NewEntry(logger).WithField("app", "scans-in-memory"))
-	storage := dao.NewStorage(postgresDB, &scansDao)
+	storage := dao.NewStorage(postgresDB, postgresDB)
 
 	// Start KubeScanner
 	kubeScanner := kube.NewKubeScanner(
@@ -60,7 +59,7 @@ func main() {
 		logrus.NewEntry(logger).WithField("app", "kube-scanner"),
 	)
 	go func() {
-		err = kubeScanner.Start(config.ScanDelay)
+		err = kubeScanner.Start(context.Background(), config.ScanDelay)
 		if err != nil {
 			logger.
 				WithField("error", err).
@@ -78,7 +77,7 @@ func main() {
 	}()
 
 	// Start httpServer.server
-	server := httpServer.NewHttpServer(config, storage, logger.WithField("app", "httpServer-server"))
+	server := httpServer.NewHttpServer(config, storage, kubeScanner, logger.WithField("app", "httpServer-server"))
 	go func() {
 		err := server.ListenAndServe()
 		if err == http.ErrServerClosed {
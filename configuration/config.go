@@ -20,14 +20,39 @@ type Config struct {
 			Timeout  int    `mapstructure:"timeout"`
 		}
 		Kubernetes struct {
-			Timeout *int `mapstructure:"timeout"`
+			Timeout        *int    `mapstructure:"timeout"`
+			Workers        int     `mapstructure:"workers"`
+			QPS            float32 `mapstructure:"qps"`
+			Burst          int     `mapstructure:"burst"`
+			CredentialsDir string  `mapstructure:"credentials_dir"`
 		}
 	} `mapstructure:"system"`
 	Logger struct {
 		Level string `mapstructure:"level"`
 	} `mapstructure:"logger"`
-	ScanDelay       int    `mapstructure:"scan_delay"`
-	JobsGrepPattern string `mapstructure:"jobs_grep_pattern"`
+	ScanDelay       int                 `mapstructure:"scan_delay"`
+	ScanTimeout     int                 `mapstructure:"scan_timeout"`
+	ScansRetention  int                 `mapstructure:"scans_retention_days"`
+	JobsGrepPattern string              `mapstructure:"jobs_grep_pattern"`
+	JobsGrepRules   []GrepRule          `mapstructure:"jobs_grep_rules"`
+	JobLogMaxSize   int                 `mapstructure:"job_log_max_size"`
+	LogLevels       map[string][]string `mapstructure:"log_levels"`
+	ErrorsTopN      int                 `mapstructure:"errors_top_n"`
+	Multiline       struct {
+		Presets      []string `mapstructure:"presets"`
+		StartPattern string   `mapstructure:"start_pattern"`
+	} `mapstructure:"multiline"`
+}
+
+// GrepRule is global named rule of jobs logs grep
+type GrepRule struct {
+	Name           string `mapstructure:"name"`
+	Pattern        string `mapstructure:"pattern"`
+	ExcludePattern string `mapstructure:"exclude_pattern"`
+	Severity       string `mapstructure:"severity"`
+	Description    string `mapstructure:"description"`
+	ContextBefore  int    `mapstructure:"context_before"`
+	ContextAfter   int    `mapstructure:"context_after"`
 }
 
 func ReadConfig(path string) (config *Config, err error) {
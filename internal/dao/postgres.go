@@ -12,23 +12,107 @@ import (
 	"time"
 )
 
-// PostgresDB is struct which implements kube.ClusterDAOI interface and provides access to PostgresSQL DB
+// PostgresDB is struct which implements kube.ClusterDAOI and kube.ScansDAOI interfaces and provides access to PostgresSQL DB
 type PostgresDB struct {
 	db     *sqlx.DB
 	logger *logrus.Entry
+	// scansRetention is number of days scans are kept in history, negative value keeps them forever
+	scansRetention int
 }
 
 type clusterView struct {
-	Config     string         `db:"config_str"`
-	Name       string         `db:"name"`
-	NameSpaces pq.StringArray `db:"namespaces"`
+	Config            string             `db:"config_str"`
+	AuthType          string             `db:"auth_type"`
+	Server            string             `db:"server"`
+	TokenFile         string             `db:"token_file"`
+	CAFile            string             `db:"ca_file"`
+	GrepRules         grepRulesColumn    `db:"grep_rules"`
+	Name              string             `db:"name"`
+	NameSpaces        pq.StringArray     `db:"namespaces"`
+	Discovered        pq.StringArray     `db:"discovered_namespaces"`
+	LogParser         string             `db:"log_parser"`
+	ScanStatuses      scanStatusesColumn `db:"scan_statuses"`
+	ScanInterval      int                `db:"scan_interval"`
+	ScanCron          string             `db:"scan_cron"`
+	ScanPaused        bool               `db:"scan_paused"`
+	DiscoveryPatterns pq.StringArray     `db:"discovery_patterns"`
+	DiscoveryRegexp   string             `db:"discovery_regexp"`
+	DiscoveryLabels   string             `db:"discovery_label_selector"`
 }
 
 func (kcv *clusterView) convertToCluster() *model.Cluster {
 	return &model.Cluster{
-		Config:     kcv.Config,
-		Name:       kcv.Name,
-		Namespaces: kcv.NameSpaces,
+		ClusterAuth: model.ClusterAuth{
+			AuthType:  kcv.AuthType,
+			Config:    kcv.Config,
+			Server:    kcv.Server,
+			TokenFile: kcv.TokenFile,
+			CAFile:    kcv.CAFile,
+		},
+		Name:                 kcv.Name,
+		Namespaces:           kcv.NameSpaces,
+		DiscoveredNamespaces: kcv.Discovered,
+		LogParser:            kcv.LogParser,
+		ScanStatuses:         kcv.ScanStatuses,
+		Schedule: model.Schedule{
+			Interval: kcv.ScanInterval,
+			Cron:     kcv.ScanCron,
+			Paused:   kcv.ScanPaused,
+		},
+		Discovery: model.NamespaceDiscovery{
+			NamePatterns:  kcv.DiscoveryPatterns,
+			NameRegexp:    kcv.DiscoveryRegexp,
+			LabelSelector: kcv.DiscoveryLabels,
+		},
+		GrepRules: kcv.GrepRules,
+	}
+}
+
+type namespaceView struct {
+	Name              string           `db:"name"`
+	ClusterName       string           `db:"cluster_name"`
+	Discovered        bool             `db:"discovered"`
+	LogParser         string           `db:"log_parser"`
+	ScanStatus        scanStatusColumn `db:"scan_status"`
+	ScanInterval      int              `db:"scan_interval"`
+	ScanCron          string           `db:"scan_cron"`
+	ScanPaused        bool             `db:"scan_paused"`
+	IncludeLabels     string           `db:"include_label_selector"`
+	ExcludeLabels     string           `db:"exclude_label_selector"`
+	FieldSelector     string           `db:"field_selector"`
+	IncludeName       string           `db:"include_name_pattern"`
+	ExcludeName       string           `db:"exclude_name_pattern"`
+	ExcludeContainers pq.StringArray   `db:"exclude_containers"`
+	GrepRules         grepRulesColumn  `db:"grep_rules"`
+	DisableEvents     bool             `db:"disable_events"`
+	DisableCronJobs   bool             `db:"disable_cronjobs"`
+}
+
+func (nv *namespaceView) convertToNamespace() *model.Namespace {
+	return &model.Namespace{
+		Name:        nv.Name,
+		ClusterName: nv.ClusterName,
+		Discovered:  nv.Discovered,
+		LogParser:   nv.LogParser,
+		ScanStatus:  nv.ScanStatus.status,
+		Schedule: model.Schedule{
+			Interval: nv.ScanInterval,
+			Cron:     nv.ScanCron,
+			Paused:   nv.ScanPaused,
+		},
+		Filters: model.PodFilters{
+			IncludeLabelSelector: nv.IncludeLabels,
+			ExcludeLabelSelector: nv.ExcludeLabels,
+			FieldSelector:        nv.FieldSelector,
+			IncludeNamePattern:   nv.IncludeName,
+			ExcludeNamePattern:   nv.ExcludeName,
+			ExcludeContainers:    nv.ExcludeContainers,
+		},
+		GrepRules: nv.GrepRules,
+		Resources: model.ScanResources{
+			DisableEvents:   nv.DisableEvents,
+			DisableCronJobs: nv.DisableCronJobs,
+		},
 	}
 }
 
@@ -44,9 +128,14 @@ func NewPostgresDB(config *configuration.Config, logger *logrus.Entry) (*Postgre
 		return nil, err
 	}
 	db.SetConnMaxLifetime(time.Duration(config.System.Postgres.Timeout) * time.Second)
+	scansRetention := config.ScansRetention
+	if scansRetention == 0 {
+		scansRetention = defaultScansRetention
+	}
 	return &PostgresDB{
-		db:     db,
-		logger: logger,
+		db:             db,
+		logger:         logger,
+		scansRetention: scansRetention,
 	}, err
 }
 
@@ -54,12 +143,13 @@ func NewPostgresDB(config *configuration.Config, logger *logrus.Entry) (*Postgre
 //
 //	To save Namespaces should be used PostgresDB.AddNamespaceToCluster method
 func (p *PostgresDB) AddCluster(cluster *model.Cluster) (*model.Cluster, error) {
-	queryRow := `SELECT * FROM create_cluster($1, $2)`
-	queryParams := []interface{}{cluster.Name, cluster.Config}
+	queryRow := `SELECT * FROM create_cluster($1, $2, $3, $4, $5, $6)`
+	queryParams := []interface{}{cluster.Name, cluster.Config, cluster.AuthType, cluster.Server, cluster.TokenFile, cluster.CAFile}
 	row := p.db.QueryRowx(queryRow, queryParams...)
 	var kcv clusterView
 	err := row.StructScan(&kcv)
-	p.logDBRequest(queryRow, queryParams)
+	p.logDBRequest(queryRow, []interface{}{cluster.Name, redactedConfig(cluster.Config), cluster.AuthType,
+		cluster.Server, cluster.TokenFile, cluster.CAFile})
 	return kcv.convertToCluster(), p.convertDbErrorToInternal(err)
 }
 
@@ -73,13 +163,14 @@ func (p *PostgresDB) GetClusterByName(clusterName string) (*model.Cluster, error
 	return kcv.convertToCluster(), p.convertDbErrorToInternal(err)
 }
 
-// EditClusterConfig change cluster config only
+// EditClusterConfig change cluster auth settings only
 //
 //	To change namespaces list where AddNamespaceToCluster and DeleteNamespaceFromCluster methods
-func (p *PostgresDB) EditClusterConfig(clusterName string, clusterConfig string) (*model.Cluster, error) {
-	queryRow := `SELECT * FROM edit_cluster($1, $2)`
-	queryParams := []interface{}{clusterName, clusterConfig}
-	p.logDBRequest(queryRow, queryParams)
+func (p *PostgresDB) EditClusterConfig(clusterName string, auth model.ClusterAuth) (*model.Cluster, error) {
+	queryRow := `SELECT * FROM edit_cluster($1, $2, $3, $4, $5, $6)`
+	queryParams := []interface{}{clusterName, auth.Config, auth.AuthType, auth.Server, auth.TokenFile, auth.CAFile}
+	p.logDBRequest(queryRow, []interface{}{clusterName, redactedConfig(auth.Config), auth.AuthType, auth.Server,
+		auth.TokenFile, auth.CAFile})
 	row := p.db.QueryRowx(queryRow, queryParams...)
 	var kcv clusterView
 	err := row.StructScan(&kcv)
@@ -116,6 +207,49 @@ func (p *PostgresDB) GetAllClusters() ([]model.Cluster, error) {
 	return allConfigs, p.convertDbErrorToInternal(err)
 }
 
+// SetClusterLogParser changes log parser used for all cluster namespaces, which have no own log parser
+func (p *PostgresDB) SetClusterLogParser(clusterName string, logParser string) (*model.Cluster, error) {
+	queryRow := `SELECT * FROM set_cluster_log_parser($1, $2)`
+	queryParams := []interface{}{clusterName, logParser}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var kcv clusterView
+	err := row.StructScan(&kcv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return kcv.convertToCluster(), nil
+}
+
+// SetClusterDiscovery changes rules of cluster namespaces discovery. Empty discovery disables it
+func (p *PostgresDB) SetClusterDiscovery(clusterName string, discovery model.NamespaceDiscovery) (*model.Cluster, error) {
+	queryRow := `SELECT * FROM set_cluster_discovery($1, $2, $3, $4)`
+	namePatterns := discovery.NamePatterns
+	if namePatterns == nil {
+		namePatterns = make([]string, 0)
+	}
+	queryParams := []interface{}{clusterName, pq.StringArray(namePatterns), discovery.NameRegexp, discovery.LabelSelector}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var kcv clusterView
+	err := row.StructScan(&kcv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return kcv.convertToCluster(), nil
+}
+
+// SyncDiscoveredNamespaces adds discovered namespaces of cluster and deletes previously discovered ones which aren't
+// in namespaces anymore together with their scans. Manually added namespaces aren't changed
+func (p *PostgresDB) SyncDiscoveredNamespaces(clusterName string, namespaces []string) error {
+	queryRow := `SELECT * FROM sync_discovered_namespaces($1, $2)`
+	queryParams := []interface{}{clusterName, pq.StringArray(namespaces)}
+	_, err := p.db.Exec(queryRow, queryParams...)
+	p.logDBRequest(queryRow, queryParams)
+	return p.convertDbErrorToInternal(err)
+}
+
+// AddNamespaceToCluster adds namespace to cluster manually. Namespace which was discovered before becomes pinned
 func (p *PostgresDB) AddNamespaceToCluster(clusterName string, namespaceName string) error {
 	queryRow := `SELECT * FROM add_namespace($1, $2)`
 	queryParams := []interface{}{namespaceName, clusterName}
@@ -132,6 +266,115 @@ func (p *PostgresDB) DeleteNamespaceFromCluster(clusterName string, namespaceNam
 	return p.convertDbErrorToInternal(err)
 }
 
+func (p *PostgresDB) GetNamespaces(clusterName string) ([]model.Namespace, error) {
+	queryRow := `SELECT * FROM get_namespaces($1)`
+	queryParams := []interface{}{clusterName}
+	rows, err := p.db.Queryx(queryRow, queryParams...)
+	p.logDBRequest(queryRow, queryParams)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	defer rows.Close()
+	namespaces := make([]model.Namespace, 0)
+	for rows.Next() {
+		var nv namespaceView
+		err = rows.StructScan(&nv)
+		if err != nil {
+			return nil, p.convertDbErrorToInternal(err)
+		}
+		namespaces = append(namespaces, *nv.convertToNamespace())
+	}
+	return namespaces, p.convertDbErrorToInternal(rows.Err())
+}
+
+func (p *PostgresDB) GetNamespace(clusterName string, namespaceName string) (*model.Namespace, error) {
+	queryRow := `SELECT * FROM get_namespace($1, $2)`
+	queryParams := []interface{}{clusterName, namespaceName}
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var nv namespaceView
+	err := row.StructScan(&nv)
+	p.logDBRequest(queryRow, queryParams)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return nv.convertToNamespace(), nil
+}
+
+// SetClusterSchedule changes scan schedule of all cluster namespaces, which have no own schedule
+func (p *PostgresDB) SetClusterSchedule(clusterName string, schedule model.Schedule) (*model.Cluster, error) {
+	queryRow := `SELECT * FROM set_cluster_schedule($1, $2, $3, $4)`
+	queryParams := []interface{}{clusterName, schedule.Interval, schedule.Cron, schedule.Paused}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var kcv clusterView
+	err := row.StructScan(&kcv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return kcv.convertToCluster(), nil
+}
+
+// SetNamespaceSchedule changes scan schedule of namespace. Schedule without interval and cron means that cluster schedule is used
+func (p *PostgresDB) SetNamespaceSchedule(clusterName string, namespaceName string, schedule model.Schedule) (*model.Namespace, error) {
+	queryRow := `SELECT * FROM set_namespace_schedule($1, $2, $3, $4, $5)`
+	queryParams := []interface{}{clusterName, namespaceName, schedule.Interval, schedule.Cron, schedule.Paused}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var nv namespaceView
+	err := row.StructScan(&nv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return nv.convertToNamespace(), nil
+}
+
+// SetNamespaceFilters replaces pods filters of namespace. Empty filters mean that all namespace pods are scanned
+func (p *PostgresDB) SetNamespaceFilters(clusterName string, namespaceName string, filters model.PodFilters) (*model.Namespace, error) {
+	queryRow := `SELECT * FROM set_namespace_filters($1, $2, $3, $4, $5, $6, $7, $8)`
+	excludeContainers := filters.ExcludeContainers
+	if excludeContainers == nil {
+		excludeContainers = make([]string, 0)
+	}
+	queryParams := []interface{}{clusterName, namespaceName, filters.IncludeLabelSelector, filters.ExcludeLabelSelector,
+		filters.FieldSelector, filters.IncludeNamePattern, filters.ExcludeNamePattern, pq.StringArray(excludeContainers)}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var nv namespaceView
+	err := row.StructScan(&nv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return nv.convertToNamespace(), nil
+}
+
+// SetNamespaceResources changes which namespace resources are scanned besides pods logs
+func (p *PostgresDB) SetNamespaceResources(clusterName string, namespaceName string, resources model.ScanResources) (*model.Namespace, error) {
+	queryRow := `SELECT * FROM set_namespace_resources($1, $2, $3, $4)`
+	queryParams := []interface{}{clusterName, namespaceName, resources.DisableEvents, resources.DisableCronJobs}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var nv namespaceView
+	err := row.StructScan(&nv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return nv.convertToNamespace(), nil
+}
+
+// SetNamespaceLogParser changes log parser of namespace. Empty logParser means that cluster log parser is used
+func (p *PostgresDB) SetNamespaceLogParser(clusterName string, namespaceName string, logParser string) (*model.Namespace, error) {
+	queryRow := `SELECT * FROM set_namespace_log_parser($1, $2, $3)`
+	queryParams := []interface{}{clusterName, namespaceName, logParser}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var nv namespaceView
+	err := row.StructScan(&nv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return nv.convertToNamespace(), nil
+}
+
 // logDBRequest write to log information about request. Method uses slog entry from PostgresDB struct
 func (p *PostgresDB) logDBRequest(queryRow string, queryParams interface{}) {
 	p.logger.WithFields(logrus.Fields{
@@ -140,6 +383,14 @@ func (p *PostgresDB) logDBRequest(queryRow string, queryParams interface{}) {
 	}).Info("db query")
 }
 
+// redactedConfig replaces kubeconfig in logged query params, kubeconfig may contain cluster credentials
+func redactedConfig(config string) string {
+	if config == "" {
+		return ""
+	}
+	return "<redacted>"
+}
+
 func (p *PostgresDB) convertDbErrorToInternal(dbError error) error {
 	if dbError == nil {
 		return dbError
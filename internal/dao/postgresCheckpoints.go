@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"scan_project/internal/model"
+	"time"
+)
+
+type checkpointView struct {
+	PodUID      string    `db:"pod_uid" json:"pod_uid"`
+	Container   string    `db:"container" json:"container"`
+	Kind        string    `db:"kind" json:"kind"`
+	ContainerID string    `db:"container_id" json:"container_id"`
+	LogTime     time.Time `db:"log_time" json:"log_time"`
+	LogLines    int       `db:"log_lines" json:"log_lines"`
+	State       []byte    `db:"state" json:"-"`
+}
+
+// checkpointRow is checkpointView passed to save_checkpoints in json array, state is kept as json
+type checkpointRow struct {
+	checkpointView
+	State json.RawMessage `json:"state"`
+}
+
+func (cv *checkpointView) convertToCheckpoint() model.Checkpoint {
+	return model.Checkpoint{
+		PodUID:      cv.PodUID,
+		Container:   cv.Container,
+		Kind:        cv.Kind,
+		ContainerID: cv.ContainerID,
+		LogTime:     cv.LogTime,
+		LogLines:    cv.LogLines,
+		State:       cv.State,
+	}
+}
+
+// GetCheckpoints returns scanner checkpoints of namespace containers
+func (p *PostgresDB) GetCheckpoints(clusterName string, namespace string) ([]model.Checkpoint, error) {
+	queryRow := `SELECT pod_uid, container, kind, container_id, log_time, log_lines, state FROM get_checkpoints($1, $2)`
+	queryParams := []interface{}{clusterName, namespace}
+	rows, err := p.db.Queryx(queryRow, queryParams...)
+	p.logDBRequest(queryRow, queryParams)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	defer rows.Close()
+	checkpoints := make([]model.Checkpoint, 0)
+	for rows.Next() {
+		var cv checkpointView
+		err = rows.StructScan(&cv)
+		if err != nil {
+			return nil, p.convertDbErrorToInternal(err)
+		}
+		checkpoints = append(checkpoints, cv.convertToCheckpoint())
+	}
+	return checkpoints, p.convertDbErrorToInternal(rows.Err())
+}
+
+// SaveCheckpoints saves checkpoints of namespace containers, the previously saved checkpoints of the same containers
+// are replaced. Checkpoints of pods which are not in podsUIDs are deleted
+func (p *PostgresDB) SaveCheckpoints(clusterName string, namespace string, checkpoints []model.Checkpoint, podsUIDs []string) error {
+	rows := make([]checkpointRow, 0, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		rows = append(rows, checkpointRow{
+			checkpointView: checkpointView{
+				PodUID:      checkpoint.PodUID,
+				Container:   checkpoint.Container,
+				Kind:        checkpoint.Kind,
+				ContainerID: checkpoint.ContainerID,
+				LogTime:     checkpoint.LogTime,
+				LogLines:    checkpoint.LogLines,
+			},
+			State: checkpoint.State,
+		})
+	}
+	rowsJson, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	queryRow := `SELECT * FROM save_checkpoints($1, $2, $3, $4)`
+	queryParams := []interface{}{clusterName, namespace, string(rowsJson), pq.StringArray(podsUIDs)}
+	_, err = p.db.Exec(queryRow, queryParams...)
+	// Checkpoints themselves are not written to log
+	p.logDBRequest(queryRow, []interface{}{clusterName, namespace, len(checkpoints), len(podsUIDs)})
+	return p.convertDbErrorToInternal(err)
+}
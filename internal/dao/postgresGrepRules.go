@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"encoding/json"
+	"scan_project/internal/model"
+)
+
+// grepRulesColumn is jsonb column with grep rules of cluster or namespace
+type grepRulesColumn []model.GrepRule
+
+func (c *grepRulesColumn) Scan(src interface{}) error {
+	*c = make(grepRulesColumn, 0)
+	if src == nil {
+		return nil
+	}
+	data, err := jsonColumnBytes(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
+
+// SetClusterGrepRules replaces grep rules of cluster, they are applied to jobs logs of all cluster namespaces
+func (p *PostgresDB) SetClusterGrepRules(clusterName string, rules []model.GrepRule) (*model.Cluster, error) {
+	rulesJson, err := marshalGrepRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	queryRow := `SELECT * FROM set_cluster_grep_rules($1, $2)`
+	queryParams := []interface{}{clusterName, rulesJson}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var kcv clusterView
+	err = row.StructScan(&kcv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return kcv.convertToCluster(), nil
+}
+
+// SetNamespaceGrepRules replaces grep rules of namespace
+func (p *PostgresDB) SetNamespaceGrepRules(clusterName string, namespaceName string, rules []model.GrepRule) (*model.Namespace, error) {
+	rulesJson, err := marshalGrepRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	queryRow := `SELECT * FROM set_namespace_grep_rules($1, $2, $3)`
+	queryParams := []interface{}{clusterName, namespaceName, rulesJson}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var nv namespaceView
+	err = row.StructScan(&nv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return nv.convertToNamespace(), nil
+}
+
+func marshalGrepRules(rules []model.GrepRule) (string, error) {
+	if rules == nil {
+		rules = make([]model.GrepRule, 0)
+	}
+	rulesJson, err := json.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	return string(rulesJson), nil
+}
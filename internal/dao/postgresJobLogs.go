@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"github.com/lib/pq"
+	"scan_project/internal/model"
+	"time"
+)
+
+type jobLogView struct {
+	JobName      string    `db:"job_name"`
+	Log          []byte    `db:"log"`
+	Size         int64     `db:"size"`
+	OriginalSize int64     `db:"original_size"`
+	Truncated    bool      `db:"truncated"`
+	SaveTime     time.Time `db:"save_time"`
+}
+
+func (jlv *jobLogView) convertToJobLog() *model.JobLog {
+	return &model.JobLog{
+		JobName:      jlv.JobName,
+		Size:         jlv.Size,
+		OriginalSize: jlv.OriginalSize,
+		Truncated:    jlv.Truncated,
+		Compressed:   jlv.Log,
+		SaveTime:     jlv.SaveTime,
+	}
+}
+
+// SaveJobLog saves compressed job log, the previously saved log of the same job is replaced
+func (p *PostgresDB) SaveJobLog(clusterName string, namespace string, jobLog *model.JobLog) error {
+	queryRow := `SELECT * FROM save_job_log($1, $2, $3, $4, $5, $6, $7)`
+	queryParams := []interface{}{clusterName, namespace, jobLog.JobName, jobLog.Compressed, jobLog.Size,
+		jobLog.OriginalSize, jobLog.Truncated}
+	_, err := p.db.Exec(queryRow, queryParams...)
+	// Log itself is not written to log
+	p.logDBRequest(queryRow, []interface{}{clusterName, namespace, jobLog.JobName, jobLog.Size, jobLog.OriginalSize, jobLog.Truncated})
+	return p.convertDbErrorToInternal(err)
+}
+
+// GetJobLog returns compressed log of the job
+func (p *PostgresDB) GetJobLog(clusterName string, namespace string, jobName string) (*model.JobLog, error) {
+	queryRow := `SELECT * FROM get_job_log($1, $2, $3)`
+	queryParams := []interface{}{clusterName, namespace, jobName}
+	p.logDBRequest(queryRow, queryParams)
+	row := p.db.QueryRowx(queryRow, queryParams...)
+	var jlv jobLogView
+	err := row.StructScan(&jlv)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	return jlv.convertToJobLog(), nil
+}
+
+// PruneJobLogs deletes logs of namespace jobs which are not in jobsNames
+func (p *PostgresDB) PruneJobLogs(clusterName string, namespace string, jobsNames []string) error {
+	queryRow := `SELECT * FROM prune_job_logs($1, $2, $3)`
+	queryParams := []interface{}{clusterName, namespace, pq.StringArray(jobsNames)}
+	_, err := p.db.Exec(queryRow, queryParams...)
+	p.logDBRequest(queryRow, queryParams)
+	return p.convertDbErrorToInternal(err)
+}
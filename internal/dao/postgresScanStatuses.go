@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"scan_project/internal/model"
+)
+
+// scanStatusesColumn is json column with scan statuses of cluster namespaces
+type scanStatusesColumn map[string]model.ScanStatus
+
+func (c *scanStatusesColumn) Scan(src interface{}) error {
+	*c = make(scanStatusesColumn)
+	if src == nil {
+		return nil
+	}
+	data, err := jsonColumnBytes(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
+
+// scanStatusColumn is json column with scan status of namespace, NULL means that namespace has never been scanned
+type scanStatusColumn struct {
+	status *model.ScanStatus
+}
+
+func (c *scanStatusColumn) Scan(src interface{}) error {
+	c.status = nil
+	if src == nil {
+		return nil
+	}
+	data, err := jsonColumnBytes(src)
+	if err != nil {
+		return err
+	}
+	c.status = &model.ScanStatus{}
+	return json.Unmarshal(data, c.status)
+}
+
+func jsonColumnBytes(src interface{}) ([]byte, error) {
+	switch v := src.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unexpected type %T of json column", src)
+	}
+}
+
+// SaveScanStatus saves result of the namespace scan attempt. Zero LastSuccessTime keeps the previous successful scan time
+func (p *PostgresDB) SaveScanStatus(clusterName string, namespace string, status model.ScanStatus) error {
+	var lastSuccessTime interface{}
+	if !status.LastSuccessTime.IsZero() {
+		lastSuccessTime = status.LastSuccessTime
+	}
+	queryRow := `SELECT * FROM save_scan_status($1, $2, $3, $4, $5, $6, $7)`
+	queryParams := []interface{}{clusterName, namespace, status.LastAttemptTime, lastSuccessTime,
+		int64(status.Duration), status.PodsCount, status.Error}
+	_, err := p.db.Exec(queryRow, queryParams...)
+	p.logDBRequest(queryRow, queryParams)
+	return p.convertDbErrorToInternal(err)
+}
@@ -0,0 +1,260 @@
+package dao
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"scan_project/internal/model"
+	"sort"
+	"time"
+)
+
+// Scan types under which scans results are saved into kube.scans table
+const (
+	servicesScanType = "services"
+	jobsScanType     = "jobs"
+	eventsScanType   = "events"
+	cronJobsScanType = "cronjobs"
+)
+
+// defaultScansRetention is number of days scans are kept in history when config doesn't set it
+const defaultScansRetention = 30
+
+// volatileScanFields are changed by every scan even if scanned objects aren't, they are ignored by scans hash
+var volatileScanFields = map[string]struct{}{
+	"scan_finish_time": {},
+	"uptime":           {},
+	"age":              {},
+}
+
+type scansView struct {
+	ScanTime     time.Time `db:"scan_time"`
+	LastScanTime time.Time `db:"last_scan_time"`
+	Scans        []byte    `db:"scans"`
+}
+
+// GetServicesScans returns services scans of the last scan run for cluster namespace
+func (p *PostgresDB) GetServicesScans(clusterName string, namespace string) ([]model.ServiceScan, error) {
+	servicesScans := make([]model.ServiceScan, 0)
+	err := p.getLastScans(clusterName, namespace, servicesScanType, &servicesScans)
+	if err != nil {
+		return nil, err
+	}
+	return servicesScans, nil
+}
+
+// GetServicesScansHistory returns all services scans runs for cluster namespace saved between from and to
+func (p *PostgresDB) GetServicesScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.ServicesScansRecord, error) {
+	views, err := p.getScansHistory(clusterName, namespace, servicesScanType, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return decodeScansHistory(views, func(view scansView, scans []model.ServiceScan) model.ServicesScansRecord {
+		return model.ServicesScansRecord{ScanTime: view.ScanTime, LastScanTime: view.LastScanTime, Scans: scans}
+	})
+}
+
+// UpdateServicesScans saves services scans as a new scan run for cluster namespace
+func (p *PostgresDB) UpdateServicesScans(clusterName string, namespace string, servicesScans []model.ServiceScan) error {
+	return p.saveScans(clusterName, namespace, servicesScanType, servicesScans)
+}
+
+// GetJobsScans returns jobs scans of the last scan run for cluster namespace
+func (p *PostgresDB) GetJobsScans(clusterName string, namespace string) ([]model.JobScan, error) {
+	jobsScans := make([]model.JobScan, 0)
+	err := p.getLastScans(clusterName, namespace, jobsScanType, &jobsScans)
+	if err != nil {
+		return nil, err
+	}
+	return jobsScans, nil
+}
+
+// GetJobsScansHistory returns all jobs scans runs for cluster namespace saved between from and to
+func (p *PostgresDB) GetJobsScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.JobsScansRecord, error) {
+	views, err := p.getScansHistory(clusterName, namespace, jobsScanType, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return decodeScansHistory(views, func(view scansView, scans []model.JobScan) model.JobsScansRecord {
+		return model.JobsScansRecord{ScanTime: view.ScanTime, LastScanTime: view.LastScanTime, Scans: scans}
+	})
+}
+
+// UpdateJobsScans saves jobs scans as a new scan run for cluster namespace
+func (p *PostgresDB) UpdateJobsScans(clusterName string, namespace string, jobsScans []model.JobScan) error {
+	return p.saveScans(clusterName, namespace, jobsScanType, jobsScans)
+}
+
+// GetEventsScans returns events scans of the last scan run for cluster namespace
+func (p *PostgresDB) GetEventsScans(clusterName string, namespace string) ([]model.EventScan, error) {
+	eventsScans := make([]model.EventScan, 0)
+	err := p.getLastScans(clusterName, namespace, eventsScanType, &eventsScans)
+	if err != nil {
+		return nil, err
+	}
+	return eventsScans, nil
+}
+
+// GetEventsScansHistory returns all events scans runs for cluster namespace saved between from and to
+func (p *PostgresDB) GetEventsScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.EventsScansRecord, error) {
+	views, err := p.getScansHistory(clusterName, namespace, eventsScanType, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return decodeScansHistory(views, func(view scansView, scans []model.EventScan) model.EventsScansRecord {
+		return model.EventsScansRecord{ScanTime: view.ScanTime, LastScanTime: view.LastScanTime, Scans: scans}
+	})
+}
+
+// UpdateEventsScans saves events scans as a new scan run for cluster namespace
+func (p *PostgresDB) UpdateEventsScans(clusterName string, namespace string, eventsScans []model.EventScan) error {
+	return p.saveScans(clusterName, namespace, eventsScanType, eventsScans)
+}
+
+// GetCronJobsScans returns CronJobs scans of the last scan run for cluster namespace
+func (p *PostgresDB) GetCronJobsScans(clusterName string, namespace string) ([]model.CronJobScan, error) {
+	cronJobsScans := make([]model.CronJobScan, 0)
+	err := p.getLastScans(clusterName, namespace, cronJobsScanType, &cronJobsScans)
+	if err != nil {
+		return nil, err
+	}
+	return cronJobsScans, nil
+}
+
+// GetCronJobsScansHistory returns all CronJobs scans runs for cluster namespace saved between from and to
+func (p *PostgresDB) GetCronJobsScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.CronJobsScansRecord, error) {
+	views, err := p.getScansHistory(clusterName, namespace, cronJobsScanType, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return decodeScansHistory(views, func(view scansView, scans []model.CronJobScan) model.CronJobsScansRecord {
+		return model.CronJobsScansRecord{ScanTime: view.ScanTime, LastScanTime: view.LastScanTime, Scans: scans}
+	})
+}
+
+// UpdateCronJobsScans saves CronJobs scans as a new scan run for cluster namespace
+func (p *PostgresDB) UpdateCronJobsScans(clusterName string, namespace string, cronJobsScans []model.CronJobScan) error {
+	return p.saveScans(clusterName, namespace, cronJobsScanType, cronJobsScans)
+}
+
+// saveScans marshals scans into json and saves them with scanType, scans of scanType older than retention are removed.
+// Scans equal to the last saved ones by scansHash aren't saved again, only their last scan time is updated, so unchanged
+// namespace doesn't grow history
+func (p *PostgresDB) saveScans(clusterName string, namespace string, scanType string, scans interface{}) error {
+	scansJson, err := json.Marshal(scans)
+	if err != nil {
+		return err
+	}
+	hash, err := scansHash(scansJson)
+	if err != nil {
+		return err
+	}
+	queryRow := `SELECT * FROM save_scans($1, $2, $3, $4, $5, $6)`
+	queryParams := []interface{}{clusterName, namespace, scanType, string(scansJson), hash, p.scansRetention}
+	_, err = p.db.Exec(queryRow, queryParams...)
+	p.logDBRequest(queryRow, []interface{}{clusterName, namespace, scanType, hash, p.scansRetention})
+	return p.convertDbErrorToInternal(err)
+}
+
+// scansHash is hash of scans json array without volatile fields. Scans are hashed in any order, because pods are
+// scanned concurrently
+func scansHash(scansJson []byte) (string, error) {
+	var scans []interface{}
+	err := json.Unmarshal(scansJson, &scans)
+	if err != nil {
+		return "", err
+	}
+	stableScans := make([]string, 0, len(scans))
+	for _, scan := range scans {
+		stableScan, err := json.Marshal(withoutVolatileFields(scan))
+		if err != nil {
+			return "", err
+		}
+		stableScans = append(stableScans, string(stableScan))
+	}
+	sort.Strings(stableScans)
+	hash := sha256.New()
+	for _, stableScan := range stableScans {
+		hash.Write([]byte(stableScan))
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// withoutVolatileFields removes volatileScanFields from decoded json value at any depth
+func withoutVolatileFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if _, ok := volatileScanFields[key]; ok {
+				delete(v, key)
+				continue
+			}
+			v[key] = withoutVolatileFields(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = withoutVolatileFields(item)
+		}
+	}
+	return value
+}
+
+// decodeScansHistory unmarshals scans of history views, record builds history record of view with its scans
+func decodeScansHistory[S any, R any](views []scansView, record func(view scansView, scans []S) R) ([]R, error) {
+	records := make([]R, 0, len(views))
+	for _, view := range views {
+		scans := make([]S, 0)
+		err := json.Unmarshal(view.Scans, &scans)
+		if err != nil {
+			return nil, err
+		}
+		if scans == nil {
+			scans = make([]S, 0)
+		}
+		records = append(records, record(view, scans))
+	}
+	return records, nil
+}
+
+// getLastScans unmarshals the last saved scans of scanType into scans. If nothing was saved, scans stays untouched
+func (p *PostgresDB) getLastScans(clusterName string, namespace string, scanType string, scans interface{}) error {
+	queryRow := `SELECT scan_time, coalesce(last_scan_time, scan_time) as last_scan_time, scans FROM get_last_scans($1, $2, $3)`
+	queryParams := []interface{}{clusterName, namespace, scanType}
+	rows, err := p.db.Queryx(queryRow, queryParams...)
+	p.logDBRequest(queryRow, queryParams)
+	if err != nil {
+		return p.convertDbErrorToInternal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return p.convertDbErrorToInternal(rows.Err())
+	}
+	var sv scansView
+	err = rows.StructScan(&sv)
+	if err != nil {
+		return p.convertDbErrorToInternal(err)
+	}
+	return json.Unmarshal(sv.Scans, scans)
+}
+
+func (p *PostgresDB) getScansHistory(clusterName string, namespace string, scanType string, from time.Time, to time.Time) ([]scansView, error) {
+	queryRow := `SELECT scan_time, coalesce(last_scan_time, scan_time) as last_scan_time, scans FROM get_scans_history($1, $2, $3, $4, $5)`
+	queryParams := []interface{}{clusterName, namespace, scanType, from, to}
+	rows, err := p.db.Queryx(queryRow, queryParams...)
+	p.logDBRequest(queryRow, queryParams)
+	if err != nil {
+		return nil, p.convertDbErrorToInternal(err)
+	}
+	defer rows.Close()
+	views := make([]scansView, 0)
+	for rows.Next() {
+		var sv scansView
+		err = rows.StructScan(&sv)
+		if err != nil {
+			return nil, p.convertDbErrorToInternal(err)
+		}
+		views = append(views, sv)
+	}
+	return views, p.convertDbErrorToInternal(rows.Err())
+}
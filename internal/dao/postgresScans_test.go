@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"reflect"
+	"scan_project/internal/model"
+	"testing"
+	"time"
+)
+
+func TestScansHash(t *testing.T) {
+	tests := []struct {
+		name      string
+		first     string
+		second    string
+		wantEqual bool
+	}{
+		{
+			name:      "scan finish time and uptime are ignored",
+			first:     `[{"service_name":"api","uptime":10,"scan_finish_time":"2023-11-09T22:00:00Z","total_lines":5}]`,
+			second:    `[{"service_name":"api","uptime":40,"scan_finish_time":"2023-11-09T22:00:30Z","total_lines":5}]`,
+			wantEqual: true,
+		},
+		{
+			name:      "nested volatile fields are ignored",
+			first:     `[{"job_name":"backup","age":1,"events":[{"reason":"Failed","age":1}]}]`,
+			second:    `[{"job_name":"backup","age":2,"events":[{"reason":"Failed","age":2}]}]`,
+			wantEqual: true,
+		},
+		{
+			name:      "order of scans is ignored",
+			first:     `[{"service_name":"api"},{"service_name":"worker"}]`,
+			second:    `[{"service_name":"worker"},{"service_name":"api"}]`,
+			wantEqual: true,
+		},
+		{
+			name:   "changed counters",
+			first:  `[{"service_name":"api","total_lines":5}]`,
+			second: `[{"service_name":"api","total_lines":6}]`,
+		},
+		{
+			name:   "new scan",
+			first:  `[{"service_name":"api"}]`,
+			second: `[{"service_name":"api"},{"service_name":"api"}]`,
+		},
+		{
+			name:      "empty scans",
+			first:     `[]`,
+			second:    `null`,
+			wantEqual: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := scansHash([]byte(tt.first))
+			if err != nil {
+				t.Fatalf("scansHash(%s) error = %v", tt.first, err)
+			}
+			second, err := scansHash([]byte(tt.second))
+			if err != nil {
+				t.Fatalf("scansHash(%s) error = %v", tt.second, err)
+			}
+			if (first == second) != tt.wantEqual {
+				t.Errorf("hashes equal = %v, want %v", first == second, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestDecodeScansHistory(t *testing.T) {
+	scanTime := time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+	lastScanTime := scanTime.Add(time.Hour)
+	tests := []struct {
+		name    string
+		views   []scansView
+		want    []model.ServicesScansRecord
+		wantErr bool
+	}{
+		{
+			name: "records keep times of views",
+			views: []scansView{
+				{ScanTime: scanTime, LastScanTime: lastScanTime, Scans: []byte(`[{"service_name":"api","total_lines":5}]`)},
+				{ScanTime: lastScanTime, LastScanTime: lastScanTime, Scans: []byte(`[]`)},
+			},
+			want: []model.ServicesScansRecord{
+				{
+					ScanTime:     scanTime,
+					LastScanTime: lastScanTime,
+					Scans:        []model.ServiceScan{{ServiceName: "api", TotalLines: 5}},
+				},
+				{ScanTime: lastScanTime, LastScanTime: lastScanTime, Scans: []model.ServiceScan{}},
+			},
+		},
+		{
+			name:  "null scans are empty",
+			views: []scansView{{ScanTime: scanTime, LastScanTime: scanTime, Scans: []byte(`null`)}},
+			want:  []model.ServicesScansRecord{{ScanTime: scanTime, LastScanTime: scanTime, Scans: []model.ServiceScan{}}},
+		},
+		{
+			name:  "no views",
+			views: []scansView{},
+			want:  []model.ServicesScansRecord{},
+		},
+		{
+			name:    "broken scans",
+			views:   []scansView{{ScanTime: scanTime, Scans: []byte(`{`)}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeScansHistory(tt.views, func(view scansView, scans []model.ServiceScan) model.ServicesScansRecord {
+				return model.ServicesScansRecord{ScanTime: view.ScanTime, LastScanTime: view.LastScanTime, Scans: scans}
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeScansHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeScansHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,7 +15,7 @@ func (s *httpServer) writeErrorResponse(w http.ResponseWriter, externalErr error
 	default:
 		err = json.NewEncoder(w).Encode(model.ServerError{
 			Code:        model.InternalServerError,
-			Description: err.Error(),
+			Description: externalErr.Error(),
 		})
 	}
 	if err != nil {
@@ -1,65 +1,369 @@
 package httpServer
 
 import (
+	"bytes"
 	"encoding/json"
 	"github.com/gorilla/mux"
 	"net/http"
+	"scan_project/internal/kube"
 	"scan_project/internal/model"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHistoryRange is used as scans history time range when "from" query parameter is not provided
+const defaultHistoryRange = 24 * time.Hour
+
+// Headers with scan status of namespace which are set in scans responses
+const (
+	scanLastAttemptHeader = "X-Scan-Last-Attempt"
+	scanLastSuccessHeader = "X-Scan-Last-Success"
+	scanErrorHeader       = "X-Scan-Error"
+	logTruncatedHeader    = "X-Log-Truncated"
 )
 
 func (s *httpServer) getJobsScans(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	clusterName, ok := vars["cluster"]
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	jobsScans, err := s.storage.GetJobsScans(clusterName, namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: jobsScans})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getJobsScansHistory(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	jobsScansHistory, err := s.storage.GetJobsScansHistory(clusterName, namespace, from, to)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: jobsScansHistory})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getServicesScans(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	servicesScans, err := s.storage.GetServicesScans(clusterName, namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: servicesScans})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+// getJobLog returns stored job log as text/plain. Part of the log can be requested with offset and limit query parameters
+// in bytes or with Range header, which is applied after them. Whole log is sent gzip encoded if client accepts it
+func (s *httpServer) getJobLog(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, _, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	job, ok := mux.Vars(r)["job"]
 	if !ok {
-		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoJobProvided))
 		return
 	}
-	namespace, ok := vars["namespace"]
+	offset, limit, err := parseLogRange(r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	jobLog, err := s.storage.GetJobLog(clusterName, namespace, job)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set(logTruncatedHeader, strconv.FormatBool(jobLog.Truncated))
+	if offset == 0 && limit == 0 && r.Header.Get("Range") == "" &&
+		strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", strconv.Itoa(len(jobLog.Compressed)))
+		_, err = w.Write(jobLog.Compressed)
+		if err != nil {
+			s.logger.WithField("error", err).Error("Failed to write job log")
+		}
+		return
+	}
+	logBytes, err := kube.ReadJobLog(jobLog)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		s.writeErrorResponse(w, err)
+		return
+	}
+	if offset > int64(len(logBytes)) {
+		offset = int64(len(logBytes))
+	}
+	logBytes = logBytes[offset:]
+	if limit > 0 && limit < int64(len(logBytes)) {
+		logBytes = logBytes[:limit]
+	}
+	http.ServeContent(w, r, "", jobLog.SaveTime, bytes.NewReader(logBytes))
+}
+
+// regrepJobScan greps stored log of the job by the current grep rules and returns job scan with the new grep result
+func (s *httpServer) regrepJobScan(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	job, ok := mux.Vars(r)["job"]
 	if !ok {
-		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoJobProvided))
 		return
 	}
-	cluster, err := s.storage.GetClusterByName(clusterName)
+	jobsScans, err := s.storage.GetJobsScans(clusterName, namespace)
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
-	if !slices.Contains(cluster.Namespaces, namespace) {
-		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoSuchNamespaceInCluster))
+	var jobScan *model.JobScan
+	for i := range jobsScans {
+		if jobsScans[i].JobName == job {
+			jobScan = &jobsScans[i]
+			break
+		}
+	}
+	if jobScan == nil {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoSuchJobInNamespace))
+		return
+	}
+	jobScan, err = s.scanner.RegrepJobScan(clusterName, namespace, *jobScan)
+	if err != nil {
+		s.writeErrorResponse(w, err)
 		return
 	}
-	jobsScans := s.storage.GetJobsScans(clusterName, namespace)
-	err = json.NewEncoder(w).Encode(jobsScans)
+	err = json.NewEncoder(w).Encode(jobScanResponse{JobScan: *jobScan, ScanStatus: status})
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
 }
 
-func (s *httpServer) getServicesScans(w http.ResponseWriter, r *http.Request) {
+// getServiceErrors returns the most frequent errors of service. Service is searched by pod name or workload name
+func (s *httpServer) getServiceErrors(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	service, ok := mux.Vars(r)["service"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoServiceProvided))
+		return
+	}
+	servicesScans, err := s.storage.GetServicesScans(clusterName, namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	var servicesErrors [][]model.ErrorFingerprint
+	for _, serviceScan := range servicesScans {
+		if serviceScan.ServiceName == service || serviceScan.WorkloadName == service {
+			servicesErrors = append(servicesErrors, serviceScan.TopErrors)
+		}
+	}
+	if servicesErrors == nil {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoSuchServiceInNamespace))
+		return
+	}
+	err = json.NewEncoder(w).Encode(serviceErrorsResponse{
+		ScanStatus: status,
+		Errors:     kube.MergeErrorFingerprints(servicesErrors, s.errorsTopN),
+	})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getServicesScansHistory(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	servicesScansHistory, err := s.storage.GetServicesScansHistory(clusterName, namespace, from, to)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: servicesScansHistory})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getEventsScans(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	eventsScans, err := s.storage.GetEventsScans(clusterName, namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: eventsScans})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getEventsScansHistory(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	eventsScansHistory, err := s.storage.GetEventsScansHistory(clusterName, namespace, from, to)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: eventsScansHistory})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getCronJobsScans(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	cronJobsScans, err := s.storage.GetCronJobsScans(clusterName, namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: cronJobsScans})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getCronJobsScansHistory(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	from, to, err := parseTimeRange(r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	cronJobsScansHistory, err := s.storage.GetCronJobsScansHistory(clusterName, namespace, from, to)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: cronJobsScansHistory})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getWorkloadsScans(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	workloadsScans, err := s.getNamespaceWorkloads(clusterName, namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(scansResponse{ScanStatus: status, Scans: workloadsScans})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getWorkloadScan(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, status, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
 	vars := mux.Vars(r)
-	clusterName, ok := vars["cluster"]
+	kind, ok := vars["kind"]
 	if !ok {
-		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoWorkloadProvided))
 		return
 	}
-	namespace, ok := vars["namespace"]
+	name, ok := vars["workload"]
 	if !ok {
-		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoWorkloadProvided))
 		return
 	}
-	cluster, err := s.storage.GetClusterByName(clusterName)
+	workloadsScans, err := s.getNamespaceWorkloads(clusterName, namespace)
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
-	if !slices.Contains(cluster.Namespaces, namespace) {
-		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoSuchNamespaceInCluster))
+	idx := slices.IndexFunc(workloadsScans, func(workload model.WorkloadScan) bool {
+		return workload.Kind == kind && workload.Name == name
+	})
+	if idx < 0 {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoSuchWorkloadInNamespace))
 		return
 	}
-	servicesScans := s.storage.GetServicesScans(clusterName, namespace)
-	err = json.NewEncoder(w).Encode(servicesScans)
+	err = json.NewEncoder(w).Encode(workloadScanResponse{WorkloadScan: workloadsScans[idx], ScanStatus: status})
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
@@ -99,18 +403,29 @@ func (s *httpServer) getCluster(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *httpServer) createCluster(w http.ResponseWriter, r *http.Request) {
-	var cluster model.Cluster
-	err := json.NewDecoder(r.Body).Decode(&cluster)
+	var clusterStruct clusterRequestStruct
+	err := json.NewDecoder(r.Body).Decode(&clusterStruct)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	cluster := clusterStruct.Cluster
+	cluster.ClusterAuth, err = s.prepareClusterAuth(cluster.ClusterAuth, clusterStruct.Context)
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
+	// Namespaces aren't saved with cluster, but access to them is checked if they are set
+	report, written := s.checkCluster(w, r, cluster.ClusterAuth, cluster.Namespaces, cluster.Discovery)
+	if written {
+		return
+	}
 	addedCluster, err := s.storage.AddCluster(&cluster)
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
-	err = json.NewEncoder(w).Encode(addedCluster)
+	err = json.NewEncoder(w).Encode(clusterResponse{Cluster: addedCluster, Validation: report})
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
@@ -180,20 +495,579 @@ func (s *httpServer) changeClusterConfig(w http.ResponseWriter, r *http.Request)
 		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
 		return
 	}
-	var clusterConfigStruct clusterConfigRequestStruct
-	err := json.NewDecoder(r.Body).Decode(&clusterConfigStruct)
+	var authStruct clusterAuthRequestStruct
+	err := json.NewDecoder(r.Body).Decode(&authStruct)
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
-	cluster, err := s.storage.EditClusterConfig(clusterName, clusterConfigStruct.Config)
+	auth, err := s.prepareClusterAuth(authStruct.ClusterAuth, authStruct.Context)
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
-	err = json.NewEncoder(w).Encode(cluster)
+	storedCluster, err := s.storage.GetClusterByName(clusterName)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	namespaces, err := s.storage.GetNamespaces(clusterName)
 	if err != nil {
 		s.writeErrorResponse(w, err)
 		return
 	}
+	namespacesNames := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		namespacesNames = append(namespacesNames, namespace.Name)
+	}
+	report, written := s.checkCluster(w, r, auth, namespacesNames, storedCluster.Discovery)
+	if written {
+		return
+	}
+	cluster, err := s.storage.EditClusterConfig(clusterName, auth)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(clusterResponse{Cluster: cluster, Validation: report})
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getNamespaces(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	namespaces, err := s.storage.GetNamespaces(clusterName)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(namespaces)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) getNamespace(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	namespaceName, ok := vars["namespace"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		return
+	}
+	namespace, err := s.storage.GetNamespace(clusterName, namespaceName)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeClusterLogParser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	var logParserStruct logParserRequestStruct
+	err := json.NewDecoder(r.Body).Decode(&logParserStruct)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	if logParserStruct.LogParser != "" && !slices.Contains(kube.LogParsersNames(), logParserStruct.LogParser) {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.UnknownLogParser))
+		return
+	}
+	cluster, err := s.storage.SetClusterLogParser(clusterName, logParserStruct.LogParser)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(cluster)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeNamespaceLogParser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	namespaceName, ok := vars["namespace"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		return
+	}
+	var logParserStruct logParserRequestStruct
+	err := json.NewDecoder(r.Body).Decode(&logParserStruct)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	if logParserStruct.LogParser != "" && !slices.Contains(kube.LogParsersNames(), logParserStruct.LogParser) {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.UnknownLogParser))
+		return
+	}
+	namespace, err := s.storage.SetNamespaceLogParser(clusterName, namespaceName, logParserStruct.LogParser)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeClusterSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	var schedule model.Schedule
+	err := json.NewDecoder(r.Body).Decode(&schedule)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = kube.ValidateSchedule(schedule)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	cluster, err := s.storage.SetClusterSchedule(clusterName, schedule)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(cluster)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeClusterDiscovery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	var discovery model.NamespaceDiscovery
+	err := json.NewDecoder(r.Body).Decode(&discovery)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = kube.ValidateNamespaceDiscovery(discovery)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	cluster, err := s.storage.SetClusterDiscovery(clusterName, discovery)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(cluster)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeClusterGrepRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	var rules []model.GrepRule
+	err := json.NewDecoder(r.Body).Decode(&rules)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = kube.ValidateGrepRules(rules)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	cluster, err := s.storage.SetClusterGrepRules(clusterName, rules)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(cluster)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeNamespaceSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	namespaceName, ok := vars["namespace"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		return
+	}
+	var schedule model.Schedule
+	err := json.NewDecoder(r.Body).Decode(&schedule)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = kube.ValidateSchedule(schedule)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	namespace, err := s.storage.SetNamespaceSchedule(clusterName, namespaceName, schedule)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeNamespaceFilters(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	namespaceName, ok := vars["namespace"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		return
+	}
+	var filters model.PodFilters
+	err := json.NewDecoder(r.Body).Decode(&filters)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = kube.ValidatePodFilters(filters)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	namespace, err := s.storage.SetNamespaceFilters(clusterName, namespaceName, filters)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeNamespaceResources(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	namespaceName, ok := vars["namespace"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		return
+	}
+	var resources model.ScanResources
+	err := json.NewDecoder(r.Body).Decode(&resources)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	namespace, err := s.storage.SetNamespaceResources(clusterName, namespaceName, resources)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) changeNamespaceGrepRules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	namespaceName, ok := vars["namespace"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoNamespaceProvided))
+		return
+	}
+	var rules []model.GrepRule
+	err := json.NewDecoder(r.Body).Decode(&rules)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = kube.ValidateGrepRules(rules)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	namespace, err := s.storage.SetNamespaceGrepRules(clusterName, namespaceName, rules)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	err = json.NewEncoder(w).Encode(namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+// getGrepRules returns global grep rules from config, they are overridden by rules of clusters and namespaces
+func (s *httpServer) getGrepRules(w http.ResponseWriter, r *http.Request) {
+	err := json.NewEncoder(w).Encode(s.scanner.GrepRules())
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) triggerClusterScan(w http.ResponseWriter, r *http.Request) {
+	clusterName, ok := mux.Vars(r)["cluster"]
+	if !ok {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoClusterNameProvided))
+		return
+	}
+	task, err := s.scanner.TriggerClusterScan(clusterName)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(task)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+func (s *httpServer) triggerNamespaceScan(w http.ResponseWriter, r *http.Request) {
+	clusterName, namespace, _, err := s.getScannedNamespace(w, r)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	task, err := s.scanner.TriggerNamespaceScan(clusterName, namespace)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(task)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+// getScanTask returns on-demand scan status. Scan must belong to cluster and namespace from request path
+func (s *httpServer) getScanTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	task, ok := s.scanner.GetScanTask(vars["id"])
+	if !ok || task.ClusterName != vars["cluster"] || task.Namespace != vars["namespace"] {
+		s.writeErrorResponse(w, model.NewServerErrorByCode(model.NoSuchScanTask))
+		return
+	}
+	err := json.NewEncoder(w).Encode(task)
+	if err != nil {
+		s.writeErrorResponse(w, err)
+		return
+	}
+}
+
+// getScannedNamespace returns cluster and namespace from request path and checks that namespace belongs to cluster.
+//
+//	Scan status of namespace is returned to be sent in response body and it's written into response headers as well,
+//	so clients can see that scans are stale. Status is nil if namespace has never been scanned
+func (s *httpServer) getScannedNamespace(w http.ResponseWriter, r *http.Request) (clusterName string, namespace string,
+	status *model.ScanStatus, err error) {
+	vars := mux.Vars(r)
+	clusterName, ok := vars["cluster"]
+	if !ok {
+		return "", "", nil, model.NewServerErrorByCode(model.NoClusterNameProvided)
+	}
+	namespace, ok = vars["namespace"]
+	if !ok {
+		return "", "", nil, model.NewServerErrorByCode(model.NoNamespaceProvided)
+	}
+	cluster, err := s.storage.GetClusterByName(clusterName)
+	if err != nil {
+		return "", "", nil, err
+	}
+	// Discovered namespaces are scanned as well as added ones
+	if !slices.Contains(cluster.Namespaces, namespace) && !slices.Contains(cluster.DiscoveredNamespaces, namespace) {
+		return "", "", nil, model.NewServerErrorByCode(model.NoSuchNamespaceInCluster)
+	}
+	if scanStatus, ok := cluster.ScanStatuses[namespace]; ok {
+		writeScanStatusHeaders(w, scanStatus)
+		status = &scanStatus
+	}
+	return clusterName, namespace, status, nil
+}
+
+// getNamespaceWorkloads aggregates the last services and jobs scans of namespace by workloads
+func (s *httpServer) getNamespaceWorkloads(clusterName string, namespace string) ([]model.WorkloadScan, error) {
+	servicesScans, err := s.storage.GetServicesScans(clusterName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	jobsScans, err := s.storage.GetJobsScans(clusterName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return kube.AggregateWorkloads(servicesScans, jobsScans), nil
+}
+
+// parseTimeRange reads "from" and "to" RFC3339 query parameters
+//
+//	By default "to" is current time and "from" is defaultHistoryRange before "to"
+func parseTimeRange(r *http.Request) (from time.Time, to time.Time, err error) {
+	query := r.URL.Query()
+	to = time.Now()
+	if toStr := query.Get("to"); toStr != "" {
+		to, err = time.Parse(time.RFC3339, toStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, model.NewServerErrorByCode(model.WrongFormatError)
+		}
+	}
+	from = to.Add(-defaultHistoryRange)
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err = time.Parse(time.RFC3339, fromStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, model.NewServerErrorByCode(model.WrongFormatError)
+		}
+	}
+	return from, to, nil
+}
+
+// writeScanStatusHeaders sets X-Scan-* headers. Zero times are omitted
+func writeScanStatusHeaders(w http.ResponseWriter, status model.ScanStatus) {
+	w.Header().Set(scanLastAttemptHeader, status.LastAttemptTime.Format(time.RFC3339))
+	if !status.LastSuccessTime.IsZero() {
+		w.Header().Set(scanLastSuccessHeader, status.LastSuccessTime.Format(time.RFC3339))
+	}
+	if status.Error != "" {
+		w.Header().Set(scanErrorHeader, status.Error)
+	}
+}
+
+// prepareClusterAuth validates auth fields and selects kubeconfig context if it's set. Kubeconfig which can't be used
+// is rejected, cluster isn't connected
+func (s *httpServer) prepareClusterAuth(auth model.ClusterAuth, kubeContext string) (model.ClusterAuth, error) {
+	err := kube.ValidateClusterAuth(auth, s.credentialsDir)
+	if err != nil {
+		return auth, err
+	}
+	if kubeContext != "" {
+		if auth.AuthType != "" && auth.AuthType != model.KubeconfigAuth {
+			return auth, model.NewServerErrorByCode(model.InvalidClusterAuth)
+		}
+		auth.Config, err = kube.SelectKubeconfigContext(auth.Config, kubeContext)
+		if err != nil {
+			return auth, err
+		}
+	}
+	return auth, kube.ValidateKubeconfig(auth)
+}
+
+// checkCluster checks cluster auth, access to namespaces and to namespaces list when discovery is enabled.
+//
+//	With dry_run query parameter the validation report is written and nothing is saved, true is returned then.
+//	Otherwise report is returned to be sent with saved cluster as warnings: cluster may be unreachable from scanner
+//	for a while or in-cluster auth may be checked from another host, so failed checks don't reject it
+func (s *httpServer) checkCluster(w http.ResponseWriter, r *http.Request, auth model.ClusterAuth, namespaces []string,
+	discovery model.NamespaceDiscovery) (*model.ClusterValidation, bool) {
+	dryRun := false
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(dryRunStr)
+		if err != nil {
+			s.writeErrorResponse(w, model.NewServerErrorByCode(model.WrongFormatError))
+			return nil, true
+		}
+	}
+	report := s.scanner.ValidateCluster(r.Context(), auth, namespaces, discovery)
+	if dryRun {
+		err := json.NewEncoder(w).Encode(report)
+		if err != nil {
+			s.writeErrorResponse(w, err)
+		}
+		return nil, true
+	}
+	if !report.Valid {
+		s.logger.Warningf("Cluster is saved with failed checks: %s", report.Error)
+	}
+	return report, false
+}
+
+// parseLogRange parses offset and limit query parameters, zero limit means the rest of the log
+func parseLogRange(r *http.Request) (offset int64, limit int64, err error) {
+	query := r.URL.Query()
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err = strconv.ParseInt(offsetStr, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, 0, model.NewServerErrorByCode(model.WrongFormatError)
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || limit < 0 {
+			return 0, 0, model.NewServerErrorByCode(model.WrongFormatError)
+		}
+	}
+	return offset, limit, nil
 }
@@ -0,0 +1,370 @@
+package httpServer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"scan_project/configuration"
+	"scan_project/internal/kube"
+	"scan_project/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: secret
+`
+
+// storageStub keeps the single cluster, methods which aren't overridden panic
+type storageStub struct {
+	kube.StorageI
+	cluster   *model.Cluster
+	saved     bool
+	jobLog    *model.JobLog
+	jobsScans []model.JobScan
+}
+
+func (ss *storageStub) AddCluster(cluster *model.Cluster) (*model.Cluster, error) {
+	ss.saved = true
+	ss.cluster = cluster
+	return cluster, nil
+}
+
+func (ss *storageStub) GetClusterByName(clusterName string) (*model.Cluster, error) {
+	if ss.cluster == nil || ss.cluster.Name != clusterName {
+		return nil, errors.New("no such cluster")
+	}
+	return ss.cluster, nil
+}
+
+func (ss *storageStub) GetJobLog(clusterName string, namespace string, jobName string) (*model.JobLog, error) {
+	if ss.jobLog == nil || ss.jobLog.JobName != jobName {
+		return nil, model.NewServerErrorByCode(model.NoSuchJobInNamespace)
+	}
+	return ss.jobLog, nil
+}
+
+func (ss *storageStub) GetJobsScans(clusterName string, namespace string) ([]model.JobScan, error) {
+	return ss.jobsScans, nil
+}
+
+// scannerStub returns the same validation report for every cluster, methods which aren't overridden panic
+type scannerStub struct {
+	KubeScannerI
+	report    model.ClusterValidation
+	regrepErr error
+}
+
+func (ss *scannerStub) ValidateCluster(ctx context.Context, auth model.ClusterAuth, namespaces []string,
+	discovery model.NamespaceDiscovery) *model.ClusterValidation {
+	report := ss.report
+	return &report
+}
+
+func (ss *scannerStub) RegrepJobScan(clusterName string, namespaceName string, jobScan model.JobScan) (*model.JobScan,
+	error) {
+	if ss.regrepErr != nil {
+		return nil, ss.regrepErr
+	}
+	jobScan.GrepVersion = "regrepped"
+	return &jobScan, nil
+}
+
+func newTestHandler(storage kube.StorageI, scanner KubeScannerI) http.Handler {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewHttpServer(&configuration.Config{}, storage, scanner, logrus.NewEntry(logger)).Handler
+}
+
+func TestCreateCluster(t *testing.T) {
+	unreachable := model.ClusterValidation{Error: "API server is unreachable: connection refused"}
+	tests := []struct {
+		name           string
+		query          string
+		config         string
+		report         model.ClusterValidation
+		wantStatus     int
+		wantSaved      bool
+		wantValidation bool
+	}{
+		{
+			name:           "checked cluster is saved",
+			config:         testKubeconfig,
+			report:         model.ClusterValidation{Valid: true},
+			wantStatus:     http.StatusOK,
+			wantSaved:      true,
+			wantValidation: true,
+		},
+		{
+			name:       "unreachable cluster is saved with warnings",
+			config:     testKubeconfig,
+			report:     unreachable,
+			wantStatus: http.StatusOK,
+			wantSaved:  true,
+		},
+		{
+			name:       "dry run doesn't save cluster",
+			query:      "?dry_run=true",
+			config:     testKubeconfig,
+			report:     unreachable,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "kubeconfig which can't be used is rejected",
+			config:     "clusters: []",
+			report:     model.ClusterValidation{Valid: true},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid dry run",
+			query:      "?dry_run=maybe",
+			config:     testKubeconfig,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &storageStub{}
+			handler := newTestHandler(storage, &scannerStub{report: tt.report})
+			body, err := json.Marshal(map[string]interface{}{"name": "test", "config": tt.config})
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/clusters"+tt.query, strings.NewReader(string(body)))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if storage.saved != tt.wantSaved {
+				t.Errorf("saved = %v, want %v", storage.saved, tt.wantSaved)
+			}
+			if !tt.wantSaved {
+				return
+			}
+			var response struct {
+				Name       string                  `json:"name"`
+				Validation model.ClusterValidation `json:"validation"`
+			}
+			err = json.Unmarshal(recorder.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatalf("response can't be decoded: %v", err)
+			}
+			if response.Name != "test" || response.Validation.Valid != tt.wantValidation ||
+				response.Validation.Error != tt.report.Error {
+				t.Errorf("response = %+v", response)
+			}
+		})
+	}
+}
+
+func TestGetJobLog(t *testing.T) {
+	const jobLog = "line 1\nline 2\nline 3\n"
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte(jobLog))
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		query        string
+		headers      map[string]string
+		wantStatus   int
+		wantBody     string
+		wantEncoding string
+		wantRange    string
+	}{
+		{
+			name:       "whole log",
+			wantStatus: http.StatusOK,
+			wantBody:   jobLog,
+		},
+		{
+			name:         "whole log is sent compressed",
+			headers:      map[string]string{"Accept-Encoding": "gzip, deflate"},
+			wantStatus:   http.StatusOK,
+			wantBody:     compressed.String(),
+			wantEncoding: "gzip",
+		},
+		{
+			name:       "offset and limit",
+			query:      "?offset=7&limit=6",
+			headers:    map[string]string{"Accept-Encoding": "gzip"},
+			wantStatus: http.StatusOK,
+			wantBody:   "line 2",
+		},
+		{
+			name:       "offset after the end",
+			query:      "?offset=100",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "range",
+			headers:    map[string]string{"Range": "bytes=7-12", "Accept-Encoding": "gzip"},
+			wantStatus: http.StatusPartialContent,
+			wantBody:   "line 2",
+			wantRange:  "bytes 7-12/21",
+		},
+		{
+			name:       "range is applied after offset",
+			query:      "?offset=7",
+			headers:    map[string]string{"Range": "bytes=-7"},
+			wantStatus: http.StatusPartialContent,
+			wantBody:   "line 3\n",
+			wantRange:  "bytes 7-13/14",
+		},
+		{
+			name:       "unsatisfiable range",
+			headers:    map[string]string{"Range": "bytes=100-"},
+			wantStatus: http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			name:       "negative offset",
+			query:      "?offset=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &storageStub{
+				cluster: &model.Cluster{Name: "test", Namespaces: []string{"default"}},
+				jobLog: &model.JobLog{
+					JobName:    "backup-1",
+					Size:       int64(len(jobLog)),
+					Compressed: compressed.Bytes(),
+				},
+			}
+			handler := newTestHandler(storage, &scannerStub{})
+			request := httptest.NewRequest(http.MethodGet,
+				"/api/v1/clusters/test/namespaces/default/jobs-scans/backup-1/log"+tt.query, nil)
+			for key, value := range tt.headers {
+				request.Header.Set(key, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantStatus >= http.StatusBadRequest {
+				return
+			}
+			if recorder.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", recorder.Body, tt.wantBody)
+			}
+			if encoding := recorder.Header().Get("Content-Encoding"); encoding != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+			if contentRange := recorder.Header().Get("Content-Range"); contentRange != tt.wantRange {
+				t.Errorf("Content-Range = %q, want %q", contentRange, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestRegrepJobScan(t *testing.T) {
+	lastAttemptTime := time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		namespace       string
+		job             string
+		regrepErr       error
+		wantStatus      int
+		wantErrCode     int
+		wantGrepVersion string
+	}{
+		{
+			name:            "job scan is regrepped",
+			namespace:       "default",
+			job:             "backup-2",
+			wantStatus:      http.StatusOK,
+			wantGrepVersion: "regrepped",
+		},
+		{
+			name:        "unknown job",
+			namespace:   "default",
+			job:         "backup-3",
+			wantStatus:  http.StatusBadRequest,
+			wantErrCode: model.NoSuchJobInNamespace,
+		},
+		{
+			name:        "namespace isn't scanned",
+			namespace:   "kube-system",
+			job:         "backup-2",
+			wantStatus:  http.StatusBadRequest,
+			wantErrCode: model.NoSuchNamespaceInCluster,
+		},
+		{
+			name:        "job log is lost",
+			namespace:   "default",
+			job:         "backup-2",
+			regrepErr:   model.NewServerErrorByCode(model.NoSuchJobInNamespace),
+			wantStatus:  http.StatusBadRequest,
+			wantErrCode: model.NoSuchJobInNamespace,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &storageStub{
+				cluster: &model.Cluster{
+					Name:         "test",
+					Namespaces:   []string{"default"},
+					ScanStatuses: map[string]model.ScanStatus{"default": {LastAttemptTime: lastAttemptTime}},
+				},
+				jobsScans: []model.JobScan{
+					{JobName: "backup-1", GrepVersion: "old"},
+					{JobName: "backup-2", GrepVersion: "old"},
+				},
+			}
+			handler := newTestHandler(storage, &scannerStub{regrepErr: tt.regrepErr})
+			request := httptest.NewRequest(http.MethodPost,
+				"/api/v1/clusters/test/namespaces/"+tt.namespace+"/jobs-scans/"+tt.job+"/regrep", nil)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var serverError model.ServerError
+				err := json.Unmarshal(recorder.Body.Bytes(), &serverError)
+				if err != nil || serverError.Code != tt.wantErrCode {
+					t.Errorf("error = %s, want code %d", recorder.Body, tt.wantErrCode)
+				}
+				return
+			}
+			var response jobScanResponse
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			if err != nil {
+				t.Fatalf("response can't be decoded: %v", err)
+			}
+			if response.JobName != tt.job || response.GrepVersion != tt.wantGrepVersion ||
+				response.ScanStatus == nil || !response.ScanStatus.LastAttemptTime.Equal(lastAttemptTime) {
+				t.Errorf("response = %+v", response)
+			}
+		})
+	}
+}
@@ -1,24 +1,44 @@
 package httpServer
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"net/http"
 	"scan_project/configuration"
 	"scan_project/internal/kube"
+	"scan_project/internal/model"
 	"time"
 )
 
+// KubeScannerI runs on-demand scans, checks clusters before they are saved, provides global grep rules and
+// greps stored jobs logs
+type KubeScannerI interface {
+	TriggerNamespaceScan(clusterName string, namespaceName string) (*model.ScanTask, error)
+	TriggerClusterScan(clusterName string) (*model.ScanTask, error)
+	GetScanTask(id string) (*model.ScanTask, bool)
+	ValidateCluster(ctx context.Context, auth model.ClusterAuth, namespaces []string,
+		discovery model.NamespaceDiscovery) *model.ClusterValidation
+	GrepRules() []model.GrepRule
+	RegrepJobScan(clusterName string, namespaceName string, jobScan model.JobScan) (*model.JobScan, error)
+}
+
 type httpServer struct {
-	logger  *logrus.Entry
-	storage kube.StorageI
+	logger         *logrus.Entry
+	storage        kube.StorageI
+	scanner        KubeScannerI
+	errorsTopN     int
+	credentialsDir string
 }
 
-func NewHttpServer(cfg *configuration.Config, storage kube.StorageI, loggerEntry *logrus.Entry) *http.Server {
+func NewHttpServer(cfg *configuration.Config, storage kube.StorageI, scanner KubeScannerI, loggerEntry *logrus.Entry) *http.Server {
 	httpServer := httpServer{
-		logger:  loggerEntry,
-		storage: storage,
+		logger:         loggerEntry,
+		storage:        storage,
+		scanner:        scanner,
+		errorsTopN:     cfg.ErrorsTopN,
+		credentialsDir: cfg.System.Kubernetes.CredentialsDir,
 	}
 	r := mux.NewRouter()
 	r.Use(httpServer.loggingMiddleware) // Log request
@@ -31,9 +51,39 @@ func NewHttpServer(cfg *configuration.Config, storage kube.StorageI, loggerEntry
 	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces", httpServer.addNamespace).Methods(http.MethodPost)
 	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}", httpServer.deleteNamespace).Methods(http.MethodDelete)
 	r.HandleFunc("/api/v1/clusters/{cluster}/config", httpServer.changeClusterConfig).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/log-parser", httpServer.changeClusterLogParser).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/schedule", httpServer.changeClusterSchedule).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/discovery", httpServer.changeClusterDiscovery).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/grep-rules", httpServer.changeClusterGrepRules).Methods(http.MethodPatch)
+	// Namespaces
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces", httpServer.getNamespaces).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}", httpServer.getNamespace).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/log-parser", httpServer.changeNamespaceLogParser).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/schedule", httpServer.changeNamespaceSchedule).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/filters", httpServer.changeNamespaceFilters).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/resources", httpServer.changeNamespaceResources).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/grep-rules", httpServer.changeNamespaceGrepRules).Methods(http.MethodPatch)
 	// Scans
 	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/jobs-scans", httpServer.getJobsScans).Methods(http.MethodGet)
 	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/services-scans", httpServer.getServicesScans).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/events-scans", httpServer.getEventsScans).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/cronjobs-scans", httpServer.getCronJobsScans).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/jobs-scans/history", httpServer.getJobsScansHistory).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/jobs-scans/{job}/log", httpServer.getJobLog).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/jobs-scans/{job}/regrep", httpServer.regrepJobScan).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/services-scans/history", httpServer.getServicesScansHistory).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/events-scans/history", httpServer.getEventsScansHistory).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/cronjobs-scans/history", httpServer.getCronJobsScansHistory).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/services-scans/{service}/errors", httpServer.getServiceErrors).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/workloads-scans", httpServer.getWorkloadsScans).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/workloads-scans/{kind}/{workload}", httpServer.getWorkloadScan).Methods(http.MethodGet)
+	// On-demand scans
+	r.HandleFunc("/api/v1/clusters/{cluster}/scans", httpServer.triggerClusterScan).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/clusters/{cluster}/scans/{id}", httpServer.getScanTask).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/scans", httpServer.triggerNamespaceScan).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/clusters/{cluster}/namespaces/{namespace}/scans/{id}", httpServer.getScanTask).Methods(http.MethodGet)
+	// Grep rules
+	r.HandleFunc("/api/v1/grep-rules", httpServer.getGrepRules).Methods(http.MethodGet)
 	return &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.System.Http.Port),
 		Handler:      r,
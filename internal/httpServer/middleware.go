@@ -20,6 +20,8 @@ func (s *httpServer) loggingMiddleware(next http.Handler) http.Handler {
 func setResponseHeadersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", scanLastAttemptHeader+", "+scanLastSuccessHeader+", "+scanErrorHeader+
+			", "+logTruncatedHeader+", Content-Range, Accept-Ranges")
 		w.Header().Set("Content-Type", "application/json")
 		next.ServeHTTP(w, r)
 	})
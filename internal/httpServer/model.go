@@ -1,9 +1,55 @@
 package httpServer
 
+import "scan_project/internal/model"
+
+// scansResponse is list of namespace scans with the last namespace scan status, so clients can see that scans are stale.
+// ScanStatus is nil if namespace has never been scanned
+type scansResponse struct {
+	ScanStatus *model.ScanStatus `json:"scan_status"`
+	Scans      interface{}       `json:"scans"`
+}
+
+// workloadScanResponse is workload scan with the last namespace scan status
+type workloadScanResponse struct {
+	model.WorkloadScan
+	ScanStatus *model.ScanStatus `json:"scan_status"`
+}
+
+// jobScanResponse is job scan with the last namespace scan status
+type jobScanResponse struct {
+	model.JobScan
+	ScanStatus *model.ScanStatus `json:"scan_status"`
+}
+
+// serviceErrorsResponse is the most frequent errors of service with the last namespace scan status
+type serviceErrorsResponse struct {
+	ScanStatus *model.ScanStatus        `json:"scan_status"`
+	Errors     []model.ErrorFingerprint `json:"errors"`
+}
+
 type namespaceRequestStruct struct {
 	Namespace string `json:"namespace"`
 }
 
-type clusterConfigRequestStruct struct {
-	Config string `json:"config"`
+// clusterRequestStruct is cluster to create, Context selects kubeconfig context used by scanner
+type clusterRequestStruct struct {
+	model.Cluster
+	Context string `json:"context"`
+}
+
+// clusterResponse is saved cluster with report of its check. Failed checks of the report are warnings,
+// they didn't prevent cluster from being saved
+type clusterResponse struct {
+	*model.Cluster
+	Validation *model.ClusterValidation `json:"validation"`
+}
+
+// clusterAuthRequestStruct is new cluster auth, Context selects kubeconfig context used by scanner
+type clusterAuthRequestStruct struct {
+	model.ClusterAuth
+	Context string `json:"context"`
+}
+
+type logParserRequestStruct struct {
+	LogParser string `json:"log_parser"`
 }
@@ -0,0 +1,87 @@
+package kube
+
+import (
+	"fmt"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"path/filepath"
+	"scan_project/internal/model"
+	"strings"
+)
+
+// defaultCredentialsDir is used when credentials directory isn't configured
+const defaultCredentialsDir = "/etc/scanner/credentials"
+
+// ValidateClusterAuth checks that auth type is known and fields required by it are set. Files of token auth must be
+// in credentialsDir, so API clients can't make scanner read other files of its host
+func ValidateClusterAuth(auth model.ClusterAuth, credentialsDir string) error {
+	var valid bool
+	switch auth.AuthType {
+	case "", model.KubeconfigAuth:
+		valid = auth.Config != ""
+	case model.InClusterAuth:
+		valid = true
+	case model.TokenAuth:
+		valid = auth.Server != "" && auth.TokenFile != ""
+		if valid {
+			_, err := credentialsFile(credentialsDir, auth.TokenFile)
+			valid = err == nil
+		}
+		if valid && auth.CAFile != "" {
+			_, err := credentialsFile(credentialsDir, auth.CAFile)
+			valid = err == nil
+		}
+	}
+	if !valid {
+		return model.NewServerErrorByCode(model.InvalidClusterAuth)
+	}
+	return nil
+}
+
+// credentialsFile resolves path of token auth file. Relative path is relative to credentialsDir, path out of it
+// is an error
+func credentialsFile(credentialsDir string, path string) (string, error) {
+	if credentialsDir == "" {
+		credentialsDir = defaultCredentialsDir
+	}
+	credentialsDir = filepath.Clean(credentialsDir)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(credentialsDir, path)
+	}
+	path = filepath.Clean(path)
+	relative, err := filepath.Rel(credentialsDir, path)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file %s is out of credentials directory %s", path, credentialsDir)
+	}
+	return path, nil
+}
+
+// restConfig builds kubernetes client config of cluster by its auth type
+func restConfig(auth model.ClusterAuth, credentialsDir string) (*rest.Config, error) {
+	switch auth.AuthType {
+	case "", model.KubeconfigAuth:
+		return clientcmd.RESTConfigFromKubeConfig([]byte(auth.Config))
+	case model.InClusterAuth:
+		return rest.InClusterConfig()
+	case model.TokenAuth:
+		tokenFile, err := credentialsFile(credentialsDir, auth.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		var caFile string
+		if auth.CAFile != "" {
+			caFile, err = credentialsFile(credentialsDir, auth.CAFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &rest.Config{
+			Host:            auth.Server,
+			BearerTokenFile: tokenFile,
+			TLSClientConfig: rest.TLSClientConfig{
+				CAFile: caFile,
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown cluster auth type %q", auth.AuthType)
+}
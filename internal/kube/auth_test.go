@@ -0,0 +1,153 @@
+package kube
+
+import (
+	"scan_project/internal/model"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+current-context: test
+users:
+- name: test
+  user:
+    token: secret
+`
+
+func TestValidateClusterAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    model.ClusterAuth
+		wantErr bool
+	}{
+		{
+			name: "kubeconfig",
+			auth: model.ClusterAuth{Config: testKubeconfig},
+		},
+		{
+			name:    "empty kubeconfig",
+			auth:    model.ClusterAuth{AuthType: model.KubeconfigAuth},
+			wantErr: true,
+		},
+		{
+			name: "in cluster",
+			auth: model.ClusterAuth{AuthType: model.InClusterAuth},
+		},
+		{
+			name: "token files in credentials directory",
+			auth: model.ClusterAuth{
+				AuthType:  model.TokenAuth,
+				Server:    "https://10.0.0.1:6443",
+				TokenFile: "/credentials/prod/token",
+				CAFile:    "prod/ca.crt",
+			},
+		},
+		{
+			name:    "token without server",
+			auth:    model.ClusterAuth{AuthType: model.TokenAuth, TokenFile: "token"},
+			wantErr: true,
+		},
+		{
+			name:    "token file is out of credentials directory",
+			auth:    model.ClusterAuth{AuthType: model.TokenAuth, Server: "https://10.0.0.1:6443", TokenFile: "/etc/passwd"},
+			wantErr: true,
+		},
+		{
+			name: "CA file escapes credentials directory",
+			auth: model.ClusterAuth{
+				AuthType:  model.TokenAuth,
+				Server:    "https://10.0.0.1:6443",
+				TokenFile: "token",
+				CAFile:    "../etc/ssl/ca.crt",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "credentials directory itself",
+			auth:    model.ClusterAuth{AuthType: model.TokenAuth, Server: "https://10.0.0.1:6443", TokenFile: "/credentials/"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown auth type",
+			auth:    model.ClusterAuth{AuthType: "password"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateClusterAuth(tt.auth, "/credentials")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateClusterAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		auth          model.ClusterAuth
+		wantHost      string
+		wantTokenFile string
+		wantCAFile    string
+		wantErr       bool
+	}{
+		{
+			name:     "kubeconfig",
+			auth:     model.ClusterAuth{Config: testKubeconfig},
+			wantHost: "https://127.0.0.1:6443",
+		},
+		{
+			name: "token files are resolved in credentials directory",
+			auth: model.ClusterAuth{
+				AuthType:  model.TokenAuth,
+				Server:    "https://10.0.0.1:6443",
+				TokenFile: "prod/token",
+				CAFile:    "/credentials/prod/./ca.crt",
+			},
+			wantHost:      "https://10.0.0.1:6443",
+			wantTokenFile: "/credentials/prod/token",
+			wantCAFile:    "/credentials/prod/ca.crt",
+		},
+		{
+			name:          "system roots without CA file",
+			auth:          model.ClusterAuth{AuthType: model.TokenAuth, Server: "https://10.0.0.1:6443", TokenFile: "token"},
+			wantHost:      "https://10.0.0.1:6443",
+			wantTokenFile: "/credentials/token",
+		},
+		{
+			name:    "stored token file out of credentials directory",
+			auth:    model.ClusterAuth{AuthType: model.TokenAuth, Server: "https://10.0.0.1:6443", TokenFile: "/root/token"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown auth type",
+			auth:    model.ClusterAuth{AuthType: "password"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := restConfig(tt.auth, "/credentials")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("restConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if config.Host != tt.wantHost || config.BearerTokenFile != tt.wantTokenFile || config.CAFile != tt.wantCAFile {
+				t.Errorf("restConfig() = host %q, token file %q, CA file %q, want %q, %q, %q", config.Host,
+					config.BearerTokenFile, config.CAFile, tt.wantHost, tt.wantTokenFile, tt.wantCAFile)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package kube
+
+import (
+	"encoding/json"
+	"k8s.io/apimachinery/pkg/types"
+	"scan_project/internal/model"
+	"sync"
+)
+
+// checkpointKey identifies pod container in the scanned cluster namespace
+type checkpointKey struct {
+	cluster   string
+	namespace string
+	podUID    types.UID
+	container string
+}
+
+// namespaceKey identifies the scanned cluster namespace
+type namespaceKey struct {
+	cluster   string
+	namespace string
+}
+
+// serviceCheckpoint remembers how far the container log was read and what was counted so far
+type serviceCheckpoint struct {
+	containerID string // changes when container restarts
+	position    logPosition
+	scan        model.ContainerScan
+	errors      errorFingerprints
+}
+
+// serviceCheckpointState is stored counters of serviceCheckpoint
+type serviceCheckpointState struct {
+	Scan   model.ContainerScan `json:"scan"`
+	Errors errorFingerprints   `json:"errors"`
+}
+
+// checkpoints stores scans state between scan runs, so only new log lines are fetched from kubernetes.
+//
+//	Checkpoints of namespace are loaded from storage by its first scan and changed checkpoints are saved after
+//	every namespace scan, so logs aren't counted again after scanner restart. Containers without checkpoint are
+//	counted from the logs kept by kubelet, model.ContainerScan CountedSince tells when counting started
+type checkpoints struct {
+	mutex           sync.Mutex
+	services        map[checkpointKey]serviceCheckpoint
+	jobs            map[checkpointKey]model.JobScan
+	loaded          map[namespaceKey]struct{}
+	changedServices map[checkpointKey]struct{} // not saved to storage yet
+	changedJobs     map[checkpointKey]struct{}
+}
+
+func newCheckpoints() *checkpoints {
+	return &checkpoints{
+		services:        make(map[checkpointKey]serviceCheckpoint),
+		jobs:            make(map[checkpointKey]model.JobScan),
+		loaded:          make(map[namespaceKey]struct{}),
+		changedServices: make(map[checkpointKey]struct{}),
+		changedJobs:     make(map[checkpointKey]struct{}),
+	}
+}
+
+// load loads checkpoints of namespace saved by the previous scanner run, only the first call for namespace reads
+// storage. Checkpoints which can't be decoded are skipped, so their containers are counted from scratch
+func (c *checkpoints) load(cluster string, namespace string, storage checkpointsDAOI) error {
+	nsKey := namespaceKey{cluster: cluster, namespace: namespace}
+	c.mutex.Lock()
+	_, ok := c.loaded[nsKey]
+	c.mutex.Unlock()
+	if ok {
+		return nil
+	}
+	saved, err := storage.GetCheckpoints(cluster, namespace)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, checkpoint := range saved {
+		key := checkpointKey{
+			cluster:   cluster,
+			namespace: namespace,
+			podUID:    types.UID(checkpoint.PodUID),
+			container: checkpoint.Container,
+		}
+		switch checkpoint.Kind {
+		case model.ServiceCheckpoint:
+			var state serviceCheckpointState
+			if json.Unmarshal(checkpoint.State, &state) != nil || state.Scan.LogTypeCountMap == nil {
+				continue
+			}
+			if state.Errors == nil {
+				state.Errors = make(errorFingerprints)
+			}
+			c.services[key] = serviceCheckpoint{
+				containerID: checkpoint.ContainerID,
+				position:    logPosition{time: checkpoint.LogTime, lines: checkpoint.LogLines},
+				scan:        state.Scan,
+				errors:      state.Errors,
+			}
+		case model.JobCheckpoint:
+			var jobScan model.JobScan
+			if json.Unmarshal(checkpoint.State, &jobScan) != nil {
+				continue
+			}
+			c.jobs[key] = jobScan
+		}
+	}
+	c.loaded[nsKey] = struct{}{}
+	return nil
+}
+
+// save saves changed checkpoints of namespace and removes saved checkpoints of pods which aren't in alivePods.
+// If they can't be saved, they stay changed and are saved by the next scan
+func (c *checkpoints) save(cluster string, namespace string, alivePods map[types.UID]struct{}, storage checkpointsDAOI) error {
+	c.mutex.Lock()
+	changed := make([]model.Checkpoint, 0)
+	for key := range c.changedServices {
+		if key.cluster != cluster || key.namespace != namespace {
+			continue
+		}
+		cp := c.services[key]
+		state, err := json.Marshal(serviceCheckpointState{Scan: cp.scan, Errors: cp.errors})
+		if err != nil {
+			c.mutex.Unlock()
+			return err
+		}
+		changed = append(changed, model.Checkpoint{
+			PodUID:      string(key.podUID),
+			Container:   key.container,
+			Kind:        model.ServiceCheckpoint,
+			ContainerID: cp.containerID,
+			LogTime:     cp.position.time,
+			LogLines:    cp.position.lines,
+			State:       state,
+		})
+	}
+	for key := range c.changedJobs {
+		if key.cluster != cluster || key.namespace != namespace {
+			continue
+		}
+		state, err := json.Marshal(c.jobs[key])
+		if err != nil {
+			c.mutex.Unlock()
+			return err
+		}
+		changed = append(changed, model.Checkpoint{
+			PodUID:    string(key.podUID),
+			Container: key.container,
+			Kind:      model.JobCheckpoint,
+			State:     state,
+		})
+	}
+	c.mutex.Unlock()
+	podsUIDs := make([]string, 0, len(alivePods))
+	for uid := range alivePods {
+		podsUIDs = append(podsUIDs, string(uid))
+	}
+	err := storage.SaveCheckpoints(cluster, namespace, changed, podsUIDs)
+	if err != nil {
+		return err
+	}
+	// Scans of the same namespace don't run simultaneously, so saved checkpoints weren't changed meanwhile
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, checkpoint := range changed {
+		key := checkpointKey{
+			cluster:   cluster,
+			namespace: namespace,
+			podUID:    types.UID(checkpoint.PodUID),
+			container: checkpoint.Container,
+		}
+		if checkpoint.Kind == model.ServiceCheckpoint {
+			delete(c.changedServices, key)
+		} else {
+			delete(c.changedJobs, key)
+		}
+	}
+	return nil
+}
+
+func (c *checkpoints) getService(key checkpointKey) (serviceCheckpoint, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cp, ok := c.services[key]
+	if ok {
+		cp.scan = copyContainerScan(cp.scan)
+		cp.errors = cp.errors.copy()
+	}
+	return cp, ok
+}
+
+func (c *checkpoints) setService(key checkpointKey, cp serviceCheckpoint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cp.scan = copyContainerScan(cp.scan)
+	cp.errors = cp.errors.copy()
+	c.services[key] = cp
+	c.changedServices[key] = struct{}{}
+}
+
+func (c *checkpoints) getJob(key checkpointKey) (model.JobScan, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	jobScan, ok := c.jobs[key]
+	return jobScan, ok
+}
+
+func (c *checkpoints) setJob(key checkpointKey, jobScan model.JobScan) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.jobs[key] = jobScan
+	c.changedJobs[key] = struct{}{}
+}
+
+// prune removes checkpoints of cluster namespace pods which are not in alivePods anymore, stored checkpoints
+// are removed by save
+func (c *checkpoints) prune(cluster string, namespace string, alivePods map[types.UID]struct{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.services {
+		if _, ok := alivePods[key.podUID]; !ok && key.cluster == cluster && key.namespace == namespace {
+			delete(c.services, key)
+			delete(c.changedServices, key)
+		}
+	}
+	for key := range c.jobs {
+		if _, ok := alivePods[key.podUID]; !ok && key.cluster == cluster && key.namespace == namespace {
+			delete(c.jobs, key)
+			delete(c.changedJobs, key)
+		}
+	}
+}
+
+// copyContainerScan returns copy of scan which doesn't share counters map and log tail with original
+func copyContainerScan(scan model.ContainerScan) model.ContainerScan {
+	countMap := make(map[string]int, len(scan.LogTypeCountMap))
+	for level, count := range scan.LogTypeCountMap {
+		countMap[level] = count
+	}
+	scan.LogTypeCountMap = countMap
+	scan.PreviousLogTail = append([]string(nil), scan.PreviousLogTail...)
+	return scan
+}
@@ -0,0 +1,353 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	appsinformers "k8s.io/client-go/informers/apps/v1"
+	batchinformers "k8s.io/client-go/informers/batch/v1"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"scan_project/internal/model"
+	"sync"
+	"time"
+)
+
+// Namespaced resources which are read by scans from informers caches
+const (
+	podsResource        = "pods"
+	replicaSetsResource = "replicasets"
+	jobsResource        = "jobs"
+	eventsResource      = "events"
+	cronJobsResource    = "cronjobs"
+)
+
+type newInformerFunc func(client kubernetes.Interface, namespace string, resyncPeriod time.Duration,
+	indexers cache.Indexers) cache.SharedIndexInformer
+
+var resourcesInformers = map[string]newInformerFunc{
+	podsResource:        coreinformers.NewPodInformer,
+	replicaSetsResource: appsinformers.NewReplicaSetInformer,
+	jobsResource:        batchinformers.NewJobInformer,
+	eventsResource:      coreinformers.NewEventInformer,
+	cronJobsResource:    batchinformers.NewCronJobInformer,
+}
+
+// clusterClient is kubernetes clientset of the cluster with informers of scanned namespaces
+type clusterClient struct {
+	auth       model.ClusterAuth // auth settings the client was built from
+	kubeClient *kubernetes.Clientset
+	// informerClient has no request timeout, so watch connections are not interrupted
+	informerClient *kubernetes.Clientset
+	syncTimeout    time.Duration
+	mutex          sync.Mutex
+	namespaces     map[string]map[string]*resourceInformer // namespace -> resource -> informer
+	// stopped client is replaced in cache, scans which still hold it can't start informers anymore
+	stopped bool
+}
+
+// resourceInformer keeps watch-updated objects of the single resource in namespace
+type resourceInformer struct {
+	informer cache.SharedIndexInformer
+	stop     chan struct{}
+	// failed is closed when resource can't be listed because of its access or absence, so sync isn't waited for
+	failed   chan struct{}
+	failOnce sync.Once
+	err      error
+}
+
+func (ri *resourceInformer) fail(err error) {
+	ri.failOnce.Do(func() {
+		ri.err = err
+		close(ri.failed)
+	})
+}
+
+// clusterClients caches clients of clusters between scans.
+//
+//	Client is rebuilt when cluster auth changes, pods informers are started and stopped with namespace membership
+type clusterClients struct {
+	mutex             sync.Mutex
+	clients           map[string]*clusterClient
+	kubernetesTimeout time.Duration
+	qps               float32
+	burst             int
+	credentialsDir    string
+	logger            *logrus.Entry
+}
+
+// newClusterClients creates clients cache. qps and burst limit requests to every cluster,
+// zero values mean client-go defaults. Token auth files are read only from credentialsDir
+func newClusterClients(kubernetesTimeout time.Duration, qps float32, burst int, credentialsDir string,
+	logger *logrus.Entry) *clusterClients {
+	return &clusterClients{
+		clients:           make(map[string]*clusterClient),
+		kubernetesTimeout: kubernetesTimeout,
+		qps:               qps,
+		burst:             burst,
+		credentialsDir:    credentialsDir,
+		logger:            logger,
+	}
+}
+
+// get returns cached client of the cluster or builds a new one if cluster is unknown or its auth was changed
+func (cc *clusterClients) get(cluster model.Cluster) (*clusterClient, error) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	client, ok := cc.clients[cluster.Name]
+	if ok && client.auth == cluster.ClusterAuth {
+		return client, nil
+	}
+	if ok {
+		cc.logger.Infof("Auth of cluster %s was changed, kubernetes client is rebuilt", cluster.Name)
+		client.stop()
+		delete(cc.clients, cluster.Name)
+	}
+	kubeRest, err := restConfig(cluster.ClusterAuth, cc.credentialsDir)
+	if err != nil {
+		return nil, err
+	}
+	kubeRest.QPS = cc.qps
+	kubeRest.Burst = cc.burst
+	informerRest := *kubeRest
+	kubeRest.Timeout = cc.kubernetesTimeout
+	kubeClient, err := kubernetes.NewForConfig(kubeRest)
+	if err != nil {
+		return nil, err
+	}
+	informerClient, err := kubernetes.NewForConfig(&informerRest)
+	if err != nil {
+		return nil, err
+	}
+	client = &clusterClient{
+		auth:           cluster.ClusterAuth,
+		kubeClient:     kubeClient,
+		informerClient: informerClient,
+		syncTimeout:    cc.kubernetesTimeout,
+		namespaces:     make(map[string]map[string]*resourceInformer),
+	}
+	cc.clients[cluster.Name] = client
+	return client, nil
+}
+
+// retain stops and forgets clients of clusters which are not in clusterNames
+func (cc *clusterClients) retain(clusterNames []string) {
+	alive := make(map[string]struct{}, len(clusterNames))
+	for _, name := range clusterNames {
+		alive[name] = struct{}{}
+	}
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+	for name, client := range cc.clients {
+		if _, ok := alive[name]; !ok {
+			client.stop()
+			delete(cc.clients, name)
+		}
+	}
+}
+
+// retainNamespaces stops informers of cluster namespaces which are not in namespaces and informers of resources
+// which aren't scanned in namespace anymore
+func (cc *clusterClients) retainNamespaces(clusterName string, namespaces []model.Namespace) {
+	cc.mutex.Lock()
+	client, ok := cc.clients[clusterName]
+	cc.mutex.Unlock()
+	if !ok {
+		return
+	}
+	alive := make(map[string]model.Namespace, len(namespaces))
+	for _, namespace := range namespaces {
+		alive[namespace.Name] = namespace
+	}
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	for name, informers := range client.namespaces {
+		namespace, ok := alive[name]
+		if !ok {
+			for _, informer := range informers {
+				close(informer.stop)
+			}
+			delete(client.namespaces, name)
+			continue
+		}
+		needed := scannedResources(namespace)
+		for resource, informer := range informers {
+			if _, ok := needed[resource]; !ok {
+				close(informer.stop)
+				delete(informers, resource)
+			}
+		}
+	}
+}
+
+// scannedResources returns resources which informers are needed by scans of namespace
+func scannedResources(namespace model.Namespace) map[string]struct{} {
+	resources := map[string]struct{}{
+		podsResource:        {},
+		replicaSetsResource: {},
+		jobsResource:        {},
+	}
+	if !namespace.Resources.DisableEvents {
+		resources[eventsResource] = struct{}{}
+	}
+	if !namespace.Resources.DisableCronJobs {
+		resources[cronJobsResource] = struct{}{}
+	}
+	return resources
+}
+
+// stopAll stops informers of all clusters
+func (cc *clusterClients) stopAll() {
+	cc.retain(nil)
+}
+
+// list returns objects of namespace resource from informer cache. Informer is started on the first call,
+// initial list is waited no longer than kubernetes timeout.
+//
+//	Informer which failed to sync is stopped and restarted by the next call. Stopped client returns error.
+//	Returned objects are shared with informer cache and must not be modified
+func (c *clusterClient) list(ctx context.Context, namespace string, resource string) ([]interface{}, error) {
+	c.mutex.Lock()
+	if c.stopped {
+		c.mutex.Unlock()
+		return nil, fmt.Errorf("client of cluster was stopped, %s of namespace %s can't be listed", resource, namespace)
+	}
+	informers, ok := c.namespaces[namespace]
+	if !ok {
+		informers = make(map[string]*resourceInformer)
+		c.namespaces[namespace] = informers
+	}
+	informer, ok := informers[resource]
+	if !ok {
+		informer = c.startInformer(namespace, resource)
+		informers[resource] = informer
+	}
+	c.mutex.Unlock()
+	if !informer.informer.HasSynced() {
+		syncCtx, cancel := context.WithTimeout(ctx, c.syncTimeout)
+		defer cancel()
+		stopWaiting := make(chan struct{})
+		go func() {
+			select {
+			case <-syncCtx.Done():
+			case <-informer.failed:
+			}
+			close(stopWaiting)
+		}()
+		if !cache.WaitForCacheSync(stopWaiting, informer.informer.HasSynced) {
+			c.mutex.Lock()
+			if c.namespaces[namespace][resource] == informer {
+				close(informer.stop)
+				delete(c.namespaces[namespace], resource)
+			}
+			c.mutex.Unlock()
+			select {
+			case <-informer.failed:
+				return nil, fmt.Errorf("failed to sync %s of namespace %s: %w", resource, namespace, informer.err)
+			default:
+				return nil, fmt.Errorf("failed to sync %s of namespace %s", resource, namespace)
+			}
+		}
+	}
+	return informer.informer.GetStore().List(), nil
+}
+
+func (c *clusterClient) startInformer(namespace string, resource string) *resourceInformer {
+	informer := &resourceInformer{
+		informer: resourcesInformers[resource](c.informerClient, namespace, 0, cache.Indexers{}),
+		stop:     make(chan struct{}),
+		failed:   make(chan struct{}),
+	}
+	_ = informer.informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		// Other errors may be transient, reflector retries them until sync timeout
+		if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) || apierrors.IsNotFound(err) {
+			informer.fail(err)
+		}
+		cache.DefaultWatchErrorHandler(r, err)
+	})
+	go informer.informer.Run(informer.stop)
+	return informer
+}
+
+// listPods returns pods of namespace from informer cache, they must not be modified
+func (c *clusterClient) listPods(ctx context.Context, namespace string) ([]*v1.Pod, error) {
+	objects, err := c.list(ctx, namespace, podsResource)
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*v1.Pod, 0, len(objects))
+	for _, object := range objects {
+		pods = append(pods, object.(*v1.Pod))
+	}
+	return pods, nil
+}
+
+// listReplicaSets returns replica sets of namespace from informer cache, they must not be modified
+func (c *clusterClient) listReplicaSets(ctx context.Context, namespace string) ([]*appsv1.ReplicaSet, error) {
+	objects, err := c.list(ctx, namespace, replicaSetsResource)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets := make([]*appsv1.ReplicaSet, 0, len(objects))
+	for _, object := range objects {
+		replicaSets = append(replicaSets, object.(*appsv1.ReplicaSet))
+	}
+	return replicaSets, nil
+}
+
+// listJobs returns jobs of namespace from informer cache, they must not be modified
+func (c *clusterClient) listJobs(ctx context.Context, namespace string) ([]*batchv1.Job, error) {
+	objects, err := c.list(ctx, namespace, jobsResource)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*batchv1.Job, 0, len(objects))
+	for _, object := range objects {
+		jobs = append(jobs, object.(*batchv1.Job))
+	}
+	return jobs, nil
+}
+
+// listEvents returns events of namespace from informer cache, they must not be modified
+func (c *clusterClient) listEvents(ctx context.Context, namespace string) ([]*v1.Event, error) {
+	objects, err := c.list(ctx, namespace, eventsResource)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*v1.Event, 0, len(objects))
+	for _, object := range objects {
+		events = append(events, object.(*v1.Event))
+	}
+	return events, nil
+}
+
+// listCronJobs returns cron jobs of namespace from informer cache, they must not be modified
+func (c *clusterClient) listCronJobs(ctx context.Context, namespace string) ([]*batchv1.CronJob, error) {
+	objects, err := c.list(ctx, namespace, cronJobsResource)
+	if err != nil {
+		return nil, err
+	}
+	cronJobs := make([]*batchv1.CronJob, 0, len(objects))
+	for _, object := range objects {
+		cronJobs = append(cronJobs, object.(*batchv1.CronJob))
+	}
+	return cronJobs, nil
+}
+
+// stop stops all informers of the client, client can't be used after it
+func (c *clusterClient) stop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stopped = true
+	for name, informers := range c.namespaces {
+		for _, informer := range informers {
+			close(informer.stop)
+		}
+		delete(c.namespaces, name)
+	}
+}
@@ -0,0 +1,58 @@
+package kube
+
+import (
+	"context"
+	"reflect"
+	"scan_project/internal/model"
+	"testing"
+)
+
+func TestStoppedClusterClient(t *testing.T) {
+	client := &clusterClient{namespaces: make(map[string]map[string]*resourceInformer)}
+	client.stop()
+	for _, resource := range []string{podsResource, replicaSetsResource, jobsResource} {
+		if _, err := client.list(context.Background(), "default", resource); err == nil {
+			t.Errorf("list(%s) of stopped client error = nil", resource)
+		}
+	}
+	if len(client.namespaces) != 0 {
+		t.Errorf("stopped client started informers: %v", client.namespaces)
+	}
+}
+
+func TestScannedResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace model.Namespace
+		want      []string
+	}{
+		{
+			name: "all resources",
+			want: []string{podsResource, replicaSetsResource, jobsResource, eventsResource, cronJobsResource},
+		},
+		{
+			name:      "events are disabled",
+			namespace: model.Namespace{Resources: model.ScanResources{DisableEvents: true}},
+			want:      []string{podsResource, replicaSetsResource, jobsResource, cronJobsResource},
+		},
+		{
+			name: "events and cron jobs are disabled",
+			namespace: model.Namespace{Resources: model.ScanResources{
+				DisableEvents:   true,
+				DisableCronJobs: true,
+			}},
+			want: []string{podsResource, replicaSetsResource, jobsResource},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make(map[string]struct{}, len(tt.want))
+			for _, resource := range tt.want {
+				want[resource] = struct{}{}
+			}
+			if got := scannedResources(tt.namespace); !reflect.DeepEqual(got, want) {
+				t.Errorf("scannedResources() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	"scan_project/internal/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// missedScheduleGrace is how long CronJob controller may be late with Job creation before schedule is missed,
+	// CronJob starting deadline is used instead when it's set
+	missedScheduleGrace = time.Minute
+	// maxMissedSchedules limits number of missed schedule times returned in model.CronJobScan
+	maxMissedSchedules = 10
+	// maxMissedSchedulesCount stops counting of missed schedules of CronJob which has been missing them for long
+	maxMissedSchedulesCount = 1000
+	// defaultFailedJobsHistoryLimit is number of failed Jobs kept by CronJob without failedJobsHistoryLimit
+	defaultFailedJobsHistoryLimit = 1
+)
+
+// cronJobRun is Job created by CronJob with time it was scheduled at
+type cronJobRun struct {
+	scheduled time.Time
+	run       *model.JobRun
+}
+
+// scanCronJobs describes health of namespace CronJobs from informer cache by Jobs found by workload resolver
+func (ks *KubeScanner) scanCronJobs(ctx context.Context, client *clusterClient, namespace string,
+	workloads *workloadResolver) ([]model.CronJobScan, error) {
+	cronJobs, err := client.listCronJobs(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	runs := workloads.cronJobsRuns()
+	now := time.Now()
+	cronJobsScans := make([]model.CronJobScan, 0, len(cronJobs))
+	for _, cronJob := range cronJobs {
+		cronJobsScans = append(cronJobsScans, cronJobScan(cronJob, runs[cronJob.Name], now))
+	}
+	sort.Slice(cronJobsScans, func(i, j int) bool {
+		return cronJobsScans[i].Name < cronJobsScans[j].Name
+	})
+	return cronJobsScans, nil
+}
+
+// cronJobsRuns groups Jobs created by CronJobs by CronJob names, runs are sorted by their schedule times
+func (wr *workloadResolver) cronJobsRuns() map[string][]cronJobRun {
+	runs := make(map[string][]cronJobRun)
+	for name, job := range wr.jobs {
+		owner := wr.jobsOwners[name]
+		if owner == nil || owner.Kind != CronJobKind {
+			continue
+		}
+		runs[owner.Name] = append(runs[owner.Name], cronJobRun{
+			scheduled: jobScheduleTime(owner.Name, job),
+			run:       jobRun(job),
+		})
+	}
+	for _, cronJobRuns := range runs {
+		sort.Slice(cronJobRuns, func(i, j int) bool {
+			return cronJobRuns[i].scheduled.Before(cronJobRuns[j].scheduled)
+		})
+	}
+	return runs
+}
+
+// jobScheduleTime returns time Job was scheduled at. CronJob controller names Jobs by CronJob name and
+// schedule time in minutes, Job creation time is used when name has other format
+func jobScheduleTime(cronJobName string, job *batchv1.Job) time.Time {
+	suffix, ok := strings.CutPrefix(job.Name, cronJobName+"-")
+	if ok {
+		minutes, err := strconv.ParseInt(suffix, 10, 64)
+		if err == nil {
+			return time.Unix(minutes*60, 0)
+		}
+	}
+	return job.CreationTimestamp.Time
+}
+
+// cronJobScan describes CronJob by its status and runs sorted by schedule time
+func cronJobScan(cronJob *batchv1.CronJob, runs []cronJobRun, now time.Time) model.CronJobScan {
+	scan := model.CronJobScan{
+		Name:            cronJob.Name,
+		Schedule:        cronJob.Spec.Schedule,
+		Suspended:       cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+		ActiveJobs:      len(cronJob.Status.Active),
+		MissedSchedules: make([]time.Time, 0),
+		Problems:        make([]string, 0),
+	}
+	scan.FailedJobsHistoryLimit = defaultFailedJobsHistoryLimit
+	if cronJob.Spec.FailedJobsHistoryLimit != nil {
+		scan.FailedJobsHistoryLimit = int(*cronJob.Spec.FailedJobsHistoryLimit)
+	}
+	if cronJob.Spec.TimeZone != nil {
+		scan.TimeZone = *cronJob.Spec.TimeZone
+	}
+	if cronJob.Status.LastScheduleTime != nil {
+		scan.LastScheduleTime = cronJob.Status.LastScheduleTime.Time
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		scan.LastSuccessfulTime = cronJob.Status.LastSuccessfulTime.Time
+	}
+	// The latest runs go first, running Jobs don't break failures streak
+	streakFinished := false
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i].run
+		if scan.LastJobName == "" {
+			scan.LastJobName = run.Name
+			scan.LastJobStatus = run.Status
+		}
+		switch run.Status {
+		case model.JobSucceeded:
+			if run.CompletionTime.After(scan.LastSuccessfulTime) {
+				scan.LastSuccessfulTime = run.CompletionTime
+			}
+			streakFinished = true
+		case model.JobFailed:
+			if !streakFinished {
+				scan.ConsecutiveFailures++
+			}
+		}
+	}
+	// Streak isn't kept between scans, so it's known only while it's shorter than failed Jobs history
+	scan.ConsecutiveFailuresCapped = !streakFinished && scan.ConsecutiveFailures > 0 &&
+		scan.ConsecutiveFailures >= scan.FailedJobsHistoryLimit
+	switch {
+	case scan.ConsecutiveFailures == 0:
+	case scan.ConsecutiveFailuresCapped:
+		scan.Problems = append(scan.Problems, fmt.Sprintf("at least %d last jobs failed, older jobs are removed by history limit",
+			scan.ConsecutiveFailures))
+	default:
+		scan.Problems = append(scan.Problems, fmt.Sprintf("%d last jobs failed", scan.ConsecutiveFailures))
+	}
+	spec := cronJob.Spec.Schedule
+	if scan.TimeZone != "" {
+		spec = "CRON_TZ=" + scan.TimeZone + " " + spec
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		scan.Problems = append(scan.Problems, fmt.Sprintf("schedule can't be parsed: %s", err))
+		scan.ScanFinishTime = now
+		return scan
+	}
+	if !scan.Suspended {
+		scan.NextScheduleTime = schedule.Next(now)
+		grace := missedScheduleGrace
+		if cronJob.Spec.StartingDeadlineSeconds != nil {
+			grace = time.Duration(*cronJob.Spec.StartingDeadlineSeconds) * time.Second
+		}
+		since := scan.LastScheduleTime
+		if since.IsZero() {
+			since = cronJob.CreationTimestamp.Time
+		}
+		scan.MissedSchedulesCount, scan.MissedSchedules = missedSchedules(schedule, since, now.Add(-grace))
+		if scan.MissedSchedulesCount != 0 {
+			scan.Problems = append(scan.Problems, fmt.Sprintf("%d schedules were missed since %s",
+				scan.MissedSchedulesCount, since.Format(time.RFC3339)))
+		}
+	}
+	scan.ScanFinishTime = now
+	return scan
+}
+
+// missedSchedules returns number of schedule times after since and before until and the latest of them
+func missedSchedules(schedule cron.Schedule, since time.Time, until time.Time) (int, []time.Time) {
+	count := 0
+	missed := make([]time.Time, 0)
+	for next := schedule.Next(since); !next.IsZero() && next.Before(until); next = schedule.Next(next) {
+		count++
+		if len(missed) == maxMissedSchedules {
+			missed = missed[1:]
+		}
+		missed = append(missed, next)
+		if count == maxMissedSchedulesCount {
+			break
+		}
+	}
+	return count, missed
+}
@@ -0,0 +1,240 @@
+package kube
+
+import (
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"scan_project/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMissedSchedules(t *testing.T) {
+	since := time.Date(2023, 11, 9, 0, 0, 0, 0, time.UTC)
+	minutes := func(from int, to int) []time.Time {
+		times := make([]time.Time, 0, to-from+1)
+		for minute := from; minute <= to; minute++ {
+			times = append(times, since.Add(time.Duration(minute)*time.Minute))
+		}
+		return times
+	}
+	tests := []struct {
+		name      string
+		schedule  string
+		until     time.Time
+		wantCount int
+		wantTimes []time.Time
+	}{
+		{
+			name:      "until is excluded",
+			schedule:  "*/10 * * * *",
+			until:     since.Add(30 * time.Minute),
+			wantCount: 2,
+			wantTimes: []time.Time{since.Add(10 * time.Minute), since.Add(20 * time.Minute)},
+		},
+		{
+			name:      "nothing missed",
+			schedule:  "0 * * * *",
+			until:     since.Add(30 * time.Minute),
+			wantCount: 0,
+			wantTimes: []time.Time{},
+		},
+		{
+			name:      "only the latest times are returned",
+			schedule:  "* * * * *",
+			until:     since.Add(20*time.Minute + time.Second),
+			wantCount: 20,
+			wantTimes: minutes(20-maxMissedSchedules+1, 20),
+		},
+		{
+			name:      "counting stops at limit",
+			schedule:  "* * * * *",
+			until:     since.Add(48 * time.Hour),
+			wantCount: maxMissedSchedulesCount,
+			wantTimes: minutes(maxMissedSchedulesCount-maxMissedSchedules+1, maxMissedSchedulesCount),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.ParseStandard(tt.schedule)
+			if err != nil {
+				t.Fatalf("ParseStandard(%q) error = %v", tt.schedule, err)
+			}
+			count, times := missedSchedules(schedule, since, tt.until)
+			if count != tt.wantCount || !reflect.DeepEqual(times, tt.wantTimes) {
+				t.Errorf("missedSchedules() = %d, %v, want %d, %v", count, times, tt.wantCount, tt.wantTimes)
+			}
+		})
+	}
+}
+
+func TestJobScheduleTime(t *testing.T) {
+	created := time.Date(2023, 11, 9, 22, 0, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		jobName string
+		want    time.Time
+	}{
+		{
+			name:    "named by controller",
+			jobName: "backup-28325640",
+			want:    time.Unix(28325640*60, 0),
+		},
+		{
+			name:    "created manually",
+			jobName: "backup-manual-run",
+			want:    created,
+		},
+		{
+			name:    "other prefix",
+			jobName: "restore-28325640",
+			want:    created,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:              tt.jobName,
+				CreationTimestamp: metav1.NewTime(created),
+			}}
+			if got := jobScheduleTime("backup", job); !got.Equal(tt.want) {
+				t.Errorf("jobScheduleTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronJobScan(t *testing.T) {
+	now := time.Date(2023, 11, 9, 22, 30, 0, 0, time.UTC)
+	lastSchedule := metav1.NewTime(time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC))
+	run := func(name string, status string) cronJobRun {
+		return cronJobRun{run: &model.JobRun{Name: name, Status: status, CompletionTime: now.Add(-time.Minute)}}
+	}
+	int32Ptr := func(value int32) *int32 {
+		return &value
+	}
+	int64Ptr := func(value int64) *int64 {
+		return &value
+	}
+	boolPtr := func(value bool) *bool {
+		return &value
+	}
+	tests := []struct {
+		name    string
+		spec    batchv1.CronJobSpec
+		status  batchv1.CronJobStatus
+		runs    []cronJobRun
+		check   func(scan model.CronJobScan) bool
+		problem string
+	}{
+		{
+			name:   "failures streak finished by success",
+			spec:   batchv1.CronJobSpec{Schedule: "0 * * * *", FailedJobsHistoryLimit: int32Ptr(3)},
+			status: batchv1.CronJobStatus{LastScheduleTime: &lastSchedule},
+			runs:   []cronJobRun{run("a", model.JobSucceeded), run("b", model.JobFailed), run("c", model.JobFailed)},
+			check: func(scan model.CronJobScan) bool {
+				return scan.ConsecutiveFailures == 2 && !scan.ConsecutiveFailuresCapped && scan.LastJobName == "c" &&
+					scan.LastSuccessfulTime.Equal(now.Add(-time.Minute)) && scan.MissedSchedulesCount == 0 &&
+					scan.NextScheduleTime.Equal(time.Date(2023, 11, 9, 23, 0, 0, 0, time.UTC))
+			},
+			problem: "2 last jobs failed",
+		},
+		{
+			name:   "failures streak is capped by history limit",
+			spec:   batchv1.CronJobSpec{Schedule: "0 * * * *"},
+			status: batchv1.CronJobStatus{LastScheduleTime: &lastSchedule},
+			runs:   []cronJobRun{run("a", model.JobFailed), run("b", model.JobRunning)},
+			check: func(scan model.CronJobScan) bool {
+				return scan.ConsecutiveFailures == 1 && scan.ConsecutiveFailuresCapped &&
+					scan.FailedJobsHistoryLimit == defaultFailedJobsHistoryLimit && scan.LastJobStatus == model.JobRunning
+			},
+			problem: "at least 1 last jobs failed",
+		},
+		{
+			name:   "no failed jobs are kept",
+			spec:   batchv1.CronJobSpec{Schedule: "0 * * * *", FailedJobsHistoryLimit: int32Ptr(0)},
+			status: batchv1.CronJobStatus{LastScheduleTime: &lastSchedule},
+			check: func(scan model.CronJobScan) bool {
+				return scan.ConsecutiveFailures == 0 && !scan.ConsecutiveFailuresCapped && len(scan.Problems) == 0
+			},
+		},
+		{
+			name:   "no failures",
+			spec:   batchv1.CronJobSpec{Schedule: "0 * * * *"},
+			status: batchv1.CronJobStatus{LastScheduleTime: &lastSchedule},
+			runs:   []cronJobRun{run("a", model.JobSucceeded)},
+			check: func(scan model.CronJobScan) bool {
+				return scan.ConsecutiveFailures == 0 && !scan.ConsecutiveFailuresCapped && len(scan.Problems) == 0
+			},
+		},
+		{
+			name: "missed schedules",
+			spec: batchv1.CronJobSpec{Schedule: "0 * * * *"},
+			status: batchv1.CronJobStatus{
+				LastScheduleTime: &metav1.Time{Time: time.Date(2023, 11, 9, 19, 0, 0, 0, time.UTC)},
+			},
+			check: func(scan model.CronJobScan) bool {
+				return scan.MissedSchedulesCount == 3 && len(scan.MissedSchedules) == 3
+			},
+			problem: "3 schedules were missed since 2023-11-09T19:00:00Z",
+		},
+		{
+			name: "starting deadline is grace of missed schedule",
+			spec: batchv1.CronJobSpec{Schedule: "28 * * * *", StartingDeadlineSeconds: int64Ptr(120)},
+			status: batchv1.CronJobStatus{
+				LastScheduleTime: &metav1.Time{Time: time.Date(2023, 11, 9, 21, 28, 0, 0, time.UTC)},
+			},
+			check: func(scan model.CronJobScan) bool {
+				return scan.MissedSchedulesCount == 0
+			},
+		},
+		{
+			name: "schedule is missed after default grace",
+			spec: batchv1.CronJobSpec{Schedule: "28 * * * *"},
+			status: batchv1.CronJobStatus{
+				LastScheduleTime: &metav1.Time{Time: time.Date(2023, 11, 9, 21, 28, 0, 0, time.UTC)},
+			},
+			check: func(scan model.CronJobScan) bool {
+				return scan.MissedSchedulesCount == 1
+			},
+			problem: "1 schedules were missed",
+		},
+		{
+			name: "suspended has no missed schedules",
+			spec: batchv1.CronJobSpec{Schedule: "0 * * * *", Suspend: boolPtr(true)},
+			status: batchv1.CronJobStatus{
+				LastScheduleTime: &metav1.Time{Time: time.Date(2023, 11, 9, 19, 0, 0, 0, time.UTC)},
+			},
+			check: func(scan model.CronJobScan) bool {
+				return scan.Suspended && scan.MissedSchedulesCount == 0 && scan.NextScheduleTime.IsZero()
+			},
+		},
+		{
+			name:    "invalid schedule",
+			spec:    batchv1.CronJobSpec{Schedule: "every hour"},
+			check:   func(scan model.CronJobScan) bool { return scan.NextScheduleTime.IsZero() },
+			problem: "schedule can't be parsed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob := &batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "backup"},
+				Spec:       tt.spec,
+				Status:     tt.status,
+			}
+			scan := cronJobScan(cronJob, tt.runs, now)
+			if !tt.check(scan) {
+				t.Errorf("cronJobScan() = %+v", scan)
+			}
+			if !scan.ScanFinishTime.Equal(now) {
+				t.Errorf("scan finish time = %v, want %v", scan.ScanFinishTime, now)
+			}
+			if tt.problem != "" && (len(scan.Problems) == 0 || !strings.HasPrefix(scan.Problems[0], tt.problem)) {
+				t.Errorf("problems = %q, want %q", scan.Problems, tt.problem)
+			}
+		})
+	}
+}
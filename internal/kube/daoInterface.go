@@ -1,6 +1,9 @@
 package kube
 
-import "scan_project/internal/model"
+import (
+	"scan_project/internal/model"
+	"time"
+)
 
 type StorageI interface {
 	ClusterDAOI
@@ -15,27 +18,73 @@ type ClusterDAOI interface {
 type ScansDAOI interface {
 	jobsScanDAOI
 	servicesScanDAOI
+	eventsScanDAOI
+	cronJobsScanDAOI
+	scanStatusDAOI
+	jobLogsDAOI
+	checkpointsDAOI
 }
 
 type kubeConfigDAOI interface {
 	AddCluster(cluster *model.Cluster) (*model.Cluster, error)
 	GetClusterByName(clusterName string) (*model.Cluster, error)
-	EditClusterConfig(clusterName string, kubeConfig string) (*model.Cluster, error)
+	EditClusterConfig(clusterName string, auth model.ClusterAuth) (*model.Cluster, error)
 	DeleteCluster(clusterName string) error
 	GetAllClusters() ([]model.Cluster, error)
+	SetClusterLogParser(clusterName string, logParser string) (*model.Cluster, error)
+	SetClusterSchedule(clusterName string, schedule model.Schedule) (*model.Cluster, error)
+	SetClusterDiscovery(clusterName string, discovery model.NamespaceDiscovery) (*model.Cluster, error)
+	SetClusterGrepRules(clusterName string, rules []model.GrepRule) (*model.Cluster, error)
 }
 
 type namespaceDAOI interface {
 	AddNamespaceToCluster(kubeConfigName string, NamespaceName string) error
 	DeleteNamespaceFromCluster(clusterName string, namespaceName string) error
+	GetNamespaces(clusterName string) ([]model.Namespace, error)
+	GetNamespace(clusterName string, namespaceName string) (*model.Namespace, error)
+	SetNamespaceLogParser(clusterName string, namespaceName string, logParser string) (*model.Namespace, error)
+	SetNamespaceSchedule(clusterName string, namespaceName string, schedule model.Schedule) (*model.Namespace, error)
+	SetNamespaceFilters(clusterName string, namespaceName string, filters model.PodFilters) (*model.Namespace, error)
+	SetNamespaceResources(clusterName string, namespaceName string, resources model.ScanResources) (*model.Namespace, error)
+	SyncDiscoveredNamespaces(clusterName string, namespaces []string) error
+	SetNamespaceGrepRules(clusterName string, namespaceName string, rules []model.GrepRule) (*model.Namespace, error)
 }
 
 type jobsScanDAOI interface {
-	GetJobsScans(clusterName string, namespace string) []model.JobScan
+	GetJobsScans(clusterName string, namespace string) ([]model.JobScan, error)
+	GetJobsScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.JobsScansRecord, error)
 	UpdateJobsScans(clusterName string, namespace string, jobsScans []model.JobScan) error
 }
 
 type servicesScanDAOI interface {
-	GetServicesScans(clusterName string, namespace string) []model.ServiceScan
+	GetServicesScans(clusterName string, namespace string) ([]model.ServiceScan, error)
+	GetServicesScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.ServicesScansRecord, error)
 	UpdateServicesScans(clusterName string, namespace string, servicesScans []model.ServiceScan) error
 }
+
+type eventsScanDAOI interface {
+	GetEventsScans(clusterName string, namespace string) ([]model.EventScan, error)
+	GetEventsScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.EventsScansRecord, error)
+	UpdateEventsScans(clusterName string, namespace string, eventsScans []model.EventScan) error
+}
+
+type cronJobsScanDAOI interface {
+	GetCronJobsScans(clusterName string, namespace string) ([]model.CronJobScan, error)
+	GetCronJobsScansHistory(clusterName string, namespace string, from time.Time, to time.Time) ([]model.CronJobsScansRecord, error)
+	UpdateCronJobsScans(clusterName string, namespace string, cronJobsScans []model.CronJobScan) error
+}
+
+type scanStatusDAOI interface {
+	SaveScanStatus(clusterName string, namespace string, status model.ScanStatus) error
+}
+
+type jobLogsDAOI interface {
+	SaveJobLog(clusterName string, namespace string, jobLog *model.JobLog) error
+	GetJobLog(clusterName string, namespace string, jobName string) (*model.JobLog, error)
+	PruneJobLogs(clusterName string, namespace string, jobsNames []string) error
+}
+
+type checkpointsDAOI interface {
+	GetCheckpoints(clusterName string, namespace string) ([]model.Checkpoint, error)
+	SaveCheckpoints(clusterName string, namespace string, checkpoints []model.Checkpoint, podsUIDs []string) error
+}
@@ -0,0 +1,141 @@
+package kube
+
+import (
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"scan_project/internal/model"
+	"strings"
+	"time"
+)
+
+const (
+	// podPendingThreshold is how long pod may be pending before it's reported as a problem
+	podPendingThreshold = time.Minute
+	// unhealthyEventReason is reason of events which kubelet reports on failed probes
+	unhealthyEventReason = "Unhealthy"
+)
+
+// Container states reported in model.ContainerDiagnostics
+const (
+	containerRunning    = "running"
+	containerWaiting    = "waiting"
+	containerTerminated = "terminated"
+)
+
+// normalWaitingReasons are reasons of containers which are starting as usual
+var normalWaitingReasons = map[string]bool{
+	"":                  true,
+	"ContainerCreating": true,
+	"PodInitializing":   true,
+}
+
+// podDiagnostics describes pod state, containers states and probe failures found in pod events
+func podDiagnostics(pod *v1.Pod, podEventsScans []model.EventScan) model.PodDiagnostics {
+	diagnostics := model.PodDiagnostics{
+		Phase:         string(pod.Status.Phase),
+		Reason:        pod.Status.Reason,
+		Message:       pod.Status.Message,
+		Containers:    make([]model.ContainerDiagnostics, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses)),
+		ProbeFailures: make([]model.ProbeFailure, 0),
+		Problems:      make([]string, 0),
+	}
+	for _, condition := range pod.Status.Conditions {
+		switch condition.Type {
+		case v1.PodReady:
+			diagnostics.Ready = condition.Status == v1.ConditionTrue
+		case v1.PodScheduled:
+			if condition.Status != v1.ConditionTrue {
+				diagnostics.PendingReason = strings.TrimSpace(condition.Reason + " " + condition.Message)
+			}
+		}
+	}
+	switch pod.Status.Phase {
+	case v1.PodPending:
+		diagnostics.PendingDuration = time.Now().Sub(pod.CreationTimestamp.Time)
+		if diagnostics.PendingDuration >= podPendingThreshold {
+			problem := fmt.Sprintf("pod is pending for %s", diagnostics.PendingDuration.Round(time.Second))
+			if diagnostics.PendingReason != "" {
+				problem += ": " + diagnostics.PendingReason
+			}
+			diagnostics.Problems = append(diagnostics.Problems, problem)
+		}
+	case v1.PodUnknown:
+		diagnostics.Problems = append(diagnostics.Problems, "pod status is unknown, node may be unreachable")
+	case v1.PodFailed:
+		if pod.Status.Reason != "" {
+			diagnostics.Problems = append(diagnostics.Problems, fmt.Sprintf("pod failed: %s %s", pod.Status.Reason, pod.Status.Message))
+		}
+	}
+	addContainers := func(statuses []v1.ContainerStatus, isInit bool) {
+		for _, status := range statuses {
+			containerDiagnostics := newContainerDiagnostics(status, isInit)
+			diagnostics.Containers = append(diagnostics.Containers, containerDiagnostics)
+			diagnostics.Problems = append(diagnostics.Problems, containerProblems(&containerDiagnostics)...)
+		}
+	}
+	addContainers(pod.Status.InitContainerStatuses, true)
+	addContainers(pod.Status.ContainerStatuses, false)
+	for _, eventScan := range podEventsScans {
+		if eventScan.InvolvedKind != PodKind || eventScan.Reason != unhealthyEventReason {
+			continue
+		}
+		// Kubelet writes messages like "Liveness probe failed: HTTP probe failed with statuscode: 500"
+		probe, _, _ := strings.Cut(eventScan.Message, " ")
+		probeFailure := model.ProbeFailure{
+			Probe:    strings.ToLower(probe),
+			Count:    eventScan.Count,
+			LastSeen: eventScan.LastSeen,
+			Message:  eventScan.Message,
+		}
+		diagnostics.ProbeFailures = append(diagnostics.ProbeFailures, probeFailure)
+		diagnostics.Problems = append(diagnostics.Problems,
+			fmt.Sprintf("%s probe failed %d times: %s", probeFailure.Probe, probeFailure.Count, probeFailure.Message))
+	}
+	return diagnostics
+}
+
+// newContainerDiagnostics describes current and the last terminated container states
+func newContainerDiagnostics(status v1.ContainerStatus, isInit bool) model.ContainerDiagnostics {
+	containerDiagnostics := model.ContainerDiagnostics{
+		ContainerName: status.Name,
+		InitContainer: isInit,
+		Ready:         status.Ready,
+		RestartsCount: int(status.RestartCount),
+	}
+	terminated := status.LastTerminationState.Terminated
+	switch {
+	case status.State.Running != nil:
+		containerDiagnostics.State = containerRunning
+	case status.State.Waiting != nil:
+		containerDiagnostics.State = containerWaiting
+		containerDiagnostics.WaitingReason = status.State.Waiting.Reason
+		containerDiagnostics.WaitingMessage = status.State.Waiting.Message
+	case status.State.Terminated != nil:
+		containerDiagnostics.State = containerTerminated
+		terminated = status.State.Terminated
+	}
+	if terminated != nil {
+		exitCode := int(terminated.ExitCode)
+		containerDiagnostics.LastTerminationReason = terminated.Reason
+		containerDiagnostics.LastExitCode = &exitCode
+		containerDiagnostics.LastTerminationTime = terminated.FinishedAt.Time
+	}
+	return containerDiagnostics
+}
+
+// containerProblems returns human-readable descriptions of container issues
+func containerProblems(containerDiagnostics *model.ContainerDiagnostics) []string {
+	var problems []string
+	if !normalWaitingReasons[containerDiagnostics.WaitingReason] {
+		problem := fmt.Sprintf("container %s is waiting: %s", containerDiagnostics.ContainerName, containerDiagnostics.WaitingReason)
+		if containerDiagnostics.WaitingMessage != "" {
+			problem += " " + containerDiagnostics.WaitingMessage
+		}
+		problems = append(problems, problem)
+	}
+	if containerDiagnostics.LastExitCode != nil && *containerDiagnostics.LastExitCode != 0 {
+		problems = append(problems, fmt.Sprintf("container %s was terminated: %s (exit code %d)",
+			containerDiagnostics.ContainerName, containerDiagnostics.LastTerminationReason, *containerDiagnostics.LastExitCode))
+	}
+	return problems
+}
@@ -0,0 +1,156 @@
+package kube
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"path"
+	"regexp"
+	"scan_project/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// discoveryInterval is how often namespaces of the same cluster are discovered
+const discoveryInterval = time.Minute
+
+// discoveries keeps time of the last namespaces discovery of clusters
+type discoveries struct {
+	mutex sync.Mutex
+	last  map[string]time.Time
+}
+
+func newDiscoveries() *discoveries {
+	return &discoveries{
+		last: make(map[string]time.Time),
+	}
+}
+
+// due checks if cluster namespaces should be discovered now
+func (d *discoveries) due(clusterName string) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return time.Since(d.last[clusterName]) >= discoveryInterval
+}
+
+// done marks cluster namespaces discovered at the given time, failed discovery isn't marked to be retried on the next scan
+func (d *discoveries) done(clusterName string, at time.Time) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.last[clusterName] = at
+}
+
+// retain forgets clusters which are not in clusterNames
+func (d *discoveries) retain(clusterNames []string) {
+	alive := make(map[string]struct{}, len(clusterNames))
+	for _, name := range clusterNames {
+		alive[name] = struct{}{}
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for name := range d.last {
+		if _, ok := alive[name]; !ok {
+			delete(d.last, name)
+		}
+	}
+}
+
+// namespaceMatcher is compiled model.NamespaceDiscovery
+type namespaceMatcher struct {
+	namePatterns []string
+	nameRegexp   *regexp.Regexp
+}
+
+// ValidateNamespaceDiscovery checks that label selector, glob patterns and regexp of discovery can be parsed
+func ValidateNamespaceDiscovery(discovery model.NamespaceDiscovery) error {
+	_, err := newNamespaceMatcher(discovery)
+	if err == nil && discovery.LabelSelector != "" {
+		_, err = labels.Parse(discovery.LabelSelector)
+	}
+	if err != nil {
+		return model.NewServerErrorByCode(model.InvalidNamespaceDiscovery)
+	}
+	return nil
+}
+
+func newNamespaceMatcher(discovery model.NamespaceDiscovery) (*namespaceMatcher, error) {
+	nm := &namespaceMatcher{namePatterns: discovery.NamePatterns}
+	for _, pattern := range discovery.NamePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	if discovery.NameRegexp != "" {
+		nameRegexp, err := regexp.Compile(discovery.NameRegexp)
+		if err != nil {
+			return nil, err
+		}
+		nm.nameRegexp = nameRegexp
+	}
+	return nm, nil
+}
+
+// matches checks if namespace name matches any of name rules, no name rules match all namespaces
+func (nm *namespaceMatcher) matches(name string) bool {
+	if len(nm.namePatterns) == 0 && nm.nameRegexp == nil {
+		return true
+	}
+	for _, pattern := range nm.namePatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return nm.nameRegexp != nil && nm.nameRegexp.MatchString(name)
+}
+
+func discoveryEnabled(discovery model.NamespaceDiscovery) bool {
+	return len(discovery.NamePatterns) != 0 || discovery.NameRegexp != "" || discovery.LabelSelector != ""
+}
+
+// discoverNamespaces finds cluster namespaces matching its discovery rules and saves them as discovered namespaces.
+//
+//	Namespaces are discovered not more often than discoveryInterval. When discovery is disabled,
+//	previously discovered namespaces are removed. Terminating namespaces aren't discovered
+func (ks *KubeScanner) discoverNamespaces(ctx context.Context, cluster model.Cluster) error {
+	enabled := discoveryEnabled(cluster.Discovery)
+	if !enabled && len(cluster.DiscoveredNamespaces) == 0 {
+		return nil
+	}
+	if !ks.discoveries.due(cluster.Name) {
+		return nil
+	}
+	started := time.Now()
+	discovered := make([]string, 0)
+	if enabled {
+		matcher, err := newNamespaceMatcher(cluster.Discovery)
+		if err != nil {
+			return err
+		}
+		client, err := ks.clients.get(cluster)
+		if err != nil {
+			return err
+		}
+		namespaces, err := client.kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+			LabelSelector: cluster.Discovery.LabelSelector,
+		})
+		if err != nil {
+			return err
+		}
+		for _, namespace := range namespaces.Items {
+			if namespace.Status.Phase == v1.NamespaceTerminating || !matcher.matches(namespace.Name) {
+				continue
+			}
+			discovered = append(discovered, namespace.Name)
+		}
+		sort.Strings(discovered)
+	}
+	ks.logger.Tracef("Discovered namespaces of cluster %s: %v", cluster.Name, discovered)
+	err := ks.storage.SyncDiscoveredNamespaces(cluster.Name, discovered)
+	if err != nil {
+		return err
+	}
+	ks.discoveries.done(cluster.Name, started)
+	return nil
+}
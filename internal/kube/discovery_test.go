@@ -0,0 +1,92 @@
+package kube
+
+import (
+	"scan_project/internal/model"
+	"testing"
+)
+
+func TestNamespaceMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		discovery model.NamespaceDiscovery
+		matched   []string
+		skipped   []string
+	}{
+		{
+			name:      "no name rules match all namespaces",
+			discovery: model.NamespaceDiscovery{LabelSelector: "team=payments"},
+			matched:   []string{"default", "payments-prod"},
+		},
+		{
+			name:      "glob patterns",
+			discovery: model.NamespaceDiscovery{NamePatterns: []string{"team-*", "*-prod"}},
+			matched:   []string{"team-a", "payments-prod"},
+			skipped:   []string{"default", "prod-payments"},
+		},
+		{
+			name:      "regexp",
+			discovery: model.NamespaceDiscovery{NameRegexp: "^(dev|stage)-[0-9]+$"},
+			matched:   []string{"dev-1", "stage-42"},
+			skipped:   []string{"dev-a", "prod-1"},
+		},
+		{
+			name:      "any of patterns and regexp",
+			discovery: model.NamespaceDiscovery{NamePatterns: []string{"team-?"}, NameRegexp: "^ops$"},
+			matched:   []string{"team-a", "ops"},
+			skipped:   []string{"team-ab", "ops-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := newNamespaceMatcher(tt.discovery)
+			if err != nil {
+				t.Fatalf("newNamespaceMatcher() error = %v", err)
+			}
+			for _, name := range tt.matched {
+				if !matcher.matches(name) {
+					t.Errorf("matches(%q) = false, want true", name)
+				}
+			}
+			for _, name := range tt.skipped {
+				if matcher.matches(name) {
+					t.Errorf("matches(%q) = true, want false", name)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateNamespaceDiscovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		discovery model.NamespaceDiscovery
+		wantErr   bool
+	}{
+		{
+			name:      "valid",
+			discovery: model.NamespaceDiscovery{NamePatterns: []string{"team-*"}, NameRegexp: "^ops$", LabelSelector: "env=prod"},
+		},
+		{
+			name:      "invalid glob pattern",
+			discovery: model.NamespaceDiscovery{NamePatterns: []string{"team-[a"}},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid regexp",
+			discovery: model.NamespaceDiscovery{NameRegexp: "ops("},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid label selector",
+			discovery: model.NamespaceDiscovery{LabelSelector: "env in (prod"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateNamespaceDiscovery(tt.discovery); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateNamespaceDiscovery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
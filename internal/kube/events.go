@@ -0,0 +1,102 @@
+package kube
+
+import (
+	"context"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"scan_project/internal/model"
+	"sort"
+	"time"
+)
+
+// scanEvents aggregates namespace events from informer cache by involved object and reason
+func (ks *KubeScanner) scanEvents(ctx context.Context, client *clusterClient, namespace string) ([]model.EventScan, error) {
+	events, err := client.listEvents(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	type eventKey struct {
+		kind   string
+		name   string
+		reason string
+	}
+	aggregated := make(map[eventKey]*model.EventScan)
+	for _, event := range events {
+		key := eventKey{
+			kind:   event.InvolvedObject.Kind,
+			name:   event.InvolvedObject.Name,
+			reason: event.Reason,
+		}
+		firstSeen, lastSeen, count := eventOccurrences(event)
+		eventScan, ok := aggregated[key]
+		if !ok {
+			eventScan = &model.EventScan{
+				InvolvedKind: key.kind,
+				InvolvedName: key.name,
+				Reason:       key.reason,
+				FirstSeen:    firstSeen,
+			}
+			aggregated[key] = eventScan
+		}
+		eventScan.Count += count
+		if firstSeen.Before(eventScan.FirstSeen) {
+			eventScan.FirstSeen = firstSeen
+		}
+		if !lastSeen.Before(eventScan.LastSeen) {
+			eventScan.LastSeen = lastSeen
+			eventScan.Type = event.Type
+			eventScan.Message = event.Message
+		}
+	}
+	eventsScans := make([]model.EventScan, 0, len(aggregated))
+	for _, eventScan := range aggregated {
+		eventsScans = append(eventsScans, *eventScan)
+	}
+	sort.Slice(eventsScans, func(i, j int) bool {
+		return eventsScans[i].LastSeen.After(eventsScans[j].LastSeen)
+	})
+	return eventsScans, nil
+}
+
+// eventOccurrences returns first and last time of event and how many times it occurred.
+//
+//	Both deprecated counters and event series are taken into account
+func eventOccurrences(event *v1.Event) (firstSeen time.Time, lastSeen time.Time, count int) {
+	firstSeen = event.FirstTimestamp.Time
+	lastSeen = event.LastTimestamp.Time
+	count = int(event.Count)
+	if firstSeen.IsZero() {
+		firstSeen = event.EventTime.Time
+	}
+	if lastSeen.IsZero() {
+		lastSeen = event.EventTime.Time
+	}
+	if event.Series != nil {
+		count = int(event.Series.Count)
+		lastSeen = event.Series.LastObservedTime.Time
+	}
+	if firstSeen.IsZero() {
+		firstSeen = event.CreationTimestamp.Time
+	}
+	if lastSeen.IsZero() {
+		lastSeen = firstSeen
+	}
+	if count == 0 {
+		count = 1
+	}
+	return firstSeen, lastSeen, count
+}
+
+// podEvents returns events of the pod and events of the pod controller (e.g. Job)
+func podEvents(eventsScans []model.EventScan, pod *v1.Pod) []model.EventScan {
+	owner := metav1.GetControllerOf(pod)
+	podEventsScans := make([]model.EventScan, 0)
+	for _, eventScan := range eventsScans {
+		isPodEvent := eventScan.InvolvedKind == PodKind && eventScan.InvolvedName == pod.Name
+		isOwnerEvent := owner != nil && eventScan.InvolvedKind == owner.Kind && eventScan.InvolvedName == owner.Name
+		if isPodEvent || isOwnerEvent {
+			podEventsScans = append(podEventsScans, eventScan)
+		}
+	}
+	return podEventsScans
+}
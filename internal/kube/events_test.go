@@ -0,0 +1,73 @@
+package kube
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestEventOccurrences(t *testing.T) {
+	created := time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+	first := created.Add(time.Minute)
+	last := created.Add(5 * time.Minute)
+	tests := []struct {
+		name          string
+		event         v1.Event
+		wantFirstSeen time.Time
+		wantLastSeen  time.Time
+		wantCount     int
+	}{
+		{
+			name: "deprecated counters",
+			event: v1.Event{
+				FirstTimestamp: metav1.NewTime(first),
+				LastTimestamp:  metav1.NewTime(last),
+				Count:          3,
+			},
+			wantFirstSeen: first,
+			wantLastSeen:  last,
+			wantCount:     3,
+		},
+		{
+			name: "event series",
+			event: v1.Event{
+				EventTime: metav1.NewMicroTime(first),
+				Series: &v1.EventSeries{
+					Count:            4,
+					LastObservedTime: metav1.NewMicroTime(last),
+				},
+			},
+			wantFirstSeen: first,
+			wantLastSeen:  last,
+			wantCount:     4,
+		},
+		{
+			name: "single event time",
+			event: v1.Event{
+				EventTime: metav1.NewMicroTime(first),
+			},
+			wantFirstSeen: first,
+			wantLastSeen:  first,
+			wantCount:     1,
+		},
+		{
+			name: "only creation time",
+			event: v1.Event{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+			},
+			wantFirstSeen: created,
+			wantLastSeen:  created,
+			wantCount:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firstSeen, lastSeen, count := eventOccurrences(&tt.event)
+			if !firstSeen.Equal(tt.wantFirstSeen) || !lastSeen.Equal(tt.wantLastSeen) || count != tt.wantCount {
+				t.Errorf("eventOccurrences() = %v, %v, %d, want %v, %v, %d", firstSeen, lastSeen, count,
+					tt.wantFirstSeen, tt.wantLastSeen, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package kube
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"regexp"
+	"scan_project/internal/model"
+)
+
+// podFilter is compiled model.PodFilters of namespace. Empty rules of filters are nil and match everything
+type podFilter struct {
+	includeLabels     labels.Selector
+	excludeLabels     labels.Selector
+	fields            fields.Selector
+	includeName       *regexp.Regexp
+	excludeName       *regexp.Regexp
+	excludeContainers map[string]struct{}
+}
+
+// ValidatePodFilters checks that selectors and name patterns of filters can be parsed
+func ValidatePodFilters(filters model.PodFilters) error {
+	_, err := newPodFilter(filters)
+	if err != nil {
+		return model.NewServerErrorByCode(model.InvalidPodFilters)
+	}
+	return nil
+}
+
+func newPodFilter(filters model.PodFilters) (*podFilter, error) {
+	var (
+		pf  = &podFilter{excludeContainers: make(map[string]struct{}, len(filters.ExcludeContainers))}
+		err error
+	)
+	if filters.IncludeLabelSelector != "" {
+		pf.includeLabels, err = labels.Parse(filters.IncludeLabelSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filters.ExcludeLabelSelector != "" {
+		pf.excludeLabels, err = labels.Parse(filters.ExcludeLabelSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filters.FieldSelector != "" {
+		pf.fields, err = fields.ParseSelector(filters.FieldSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filters.IncludeNamePattern != "" {
+		pf.includeName, err = regexp.Compile(filters.IncludeNamePattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filters.ExcludeNamePattern != "" {
+		pf.excludeName, err = regexp.Compile(filters.ExcludeNamePattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, container := range filters.ExcludeContainers {
+		pf.excludeContainers[container] = struct{}{}
+	}
+	return pf, nil
+}
+
+// matches checks if pod should be scanned. Pod is scanned if it matches all include rules and none of exclude rules
+func (pf *podFilter) matches(pod *v1.Pod) bool {
+	podLabels := labels.Set(pod.Labels)
+	if pf.includeLabels != nil && !pf.includeLabels.Matches(podLabels) {
+		return false
+	}
+	if pf.excludeLabels != nil && pf.excludeLabels.Matches(podLabels) {
+		return false
+	}
+	if pf.fields != nil && !pf.fields.Matches(podFields(pod)) {
+		return false
+	}
+	if pf.includeName != nil && !pf.includeName.MatchString(pod.Name) {
+		return false
+	}
+	if pf.excludeName != nil && pf.excludeName.MatchString(pod.Name) {
+		return false
+	}
+	return true
+}
+
+// scanContainer checks if logs of pod container should be scanned
+func (pf *podFilter) scanContainer(name string) bool {
+	_, excluded := pf.excludeContainers[name]
+	return !excluded
+}
+
+// jobContainer returns container which log is scanned for completed pod: the default one or, if it's excluded,
+// the first not excluded container. Empty name means that all pod containers are excluded
+func (pf *podFilter) jobContainer(pod *v1.Pod) string {
+	container := defaultContainerName(pod)
+	if pf.scanContainer(container) {
+		return container
+	}
+	for _, c := range pod.Spec.Containers {
+		if pf.scanContainer(c.Name) {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// podFields returns pod fields supported by kubernetes API field selectors, so pods from informer cache are
+// filtered the same way as they would be by API server
+func podFields(pod *v1.Pod) fields.Set {
+	return fields.Set{
+		"metadata.name":            pod.Name,
+		"metadata.namespace":       pod.Namespace,
+		"spec.nodeName":            pod.Spec.NodeName,
+		"spec.restartPolicy":       string(pod.Spec.RestartPolicy),
+		"spec.schedulerName":       pod.Spec.SchedulerName,
+		"spec.serviceAccountName":  pod.Spec.ServiceAccountName,
+		"status.phase":             string(pod.Status.Phase),
+		"status.podIP":             pod.Status.PodIP,
+		"status.nominatedNodeName": pod.Status.NominatedNodeName,
+	}
+}
@@ -0,0 +1,159 @@
+package kube
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"scan_project/internal/model"
+	"testing"
+)
+
+func TestPodFilterMatches(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-7d9f8b-x2x4z",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "api", "tier": "backend"},
+		},
+		Spec:   v1.PodSpec{RestartPolicy: v1.RestartPolicyAlways},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	tests := []struct {
+		name    string
+		filters model.PodFilters
+		want    bool
+	}{
+		{
+			name: "empty filters match everything",
+			want: true,
+		},
+		{
+			name:    "include labels",
+			filters: model.PodFilters{IncludeLabelSelector: "app in (api, web)"},
+			want:    true,
+		},
+		{
+			name:    "include labels don't match",
+			filters: model.PodFilters{IncludeLabelSelector: "app=web"},
+			want:    false,
+		},
+		{
+			name:    "exclude labels",
+			filters: model.PodFilters{IncludeLabelSelector: "app=api", ExcludeLabelSelector: "tier=backend"},
+			want:    false,
+		},
+		{
+			name:    "field selector",
+			filters: model.PodFilters{FieldSelector: "status.phase=Running,spec.restartPolicy!=Never"},
+			want:    true,
+		},
+		{
+			name:    "field selector doesn't match",
+			filters: model.PodFilters{FieldSelector: "status.phase=Succeeded"},
+			want:    false,
+		},
+		{
+			name:    "include name",
+			filters: model.PodFilters{IncludeNamePattern: "^api-"},
+			want:    true,
+		},
+		{
+			name:    "exclude name wins over include name",
+			filters: model.PodFilters{IncludeNamePattern: "^api-", ExcludeNamePattern: "x2x4z$"},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newPodFilter(tt.filters)
+			if err != nil {
+				t.Fatalf("newPodFilter() error = %v", err)
+			}
+			if got := filter.matches(pod); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePodFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters model.PodFilters
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			filters: model.PodFilters{IncludeLabelSelector: "app=api", FieldSelector: "status.phase=Running"},
+		},
+		{
+			name:    "invalid label selector",
+			filters: model.PodFilters{ExcludeLabelSelector: "app in (api"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid field selector",
+			filters: model.PodFilters{FieldSelector: "status.phase"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid name pattern",
+			filters: model.PodFilters{IncludeNamePattern: "api-("},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePodFilters(tt.filters); (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePodFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPodFilterJobContainer(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main"}, {Name: "sidecar"}}}}
+	annotated := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{defaultContainerAnnotation: "sidecar"}},
+		Spec:       pod.Spec,
+	}
+	tests := []struct {
+		name              string
+		pod               *v1.Pod
+		excludeContainers []string
+		want              string
+	}{
+		{
+			name: "the first container",
+			pod:  pod,
+			want: "main",
+		},
+		{
+			name: "default container annotation",
+			pod:  annotated,
+			want: "sidecar",
+		},
+		{
+			name:              "default container is excluded",
+			pod:               pod,
+			excludeContainers: []string{"main"},
+			want:              "sidecar",
+		},
+		{
+			name:              "all containers are excluded",
+			pod:               pod,
+			excludeContainers: []string{"main", "sidecar"},
+			want:              "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newPodFilter(model.PodFilters{ExcludeContainers: tt.excludeContainers})
+			if err != nil {
+				t.Fatalf("newPodFilter() error = %v", err)
+			}
+			if got := filter.jobContainer(tt.pod); got != tt.want {
+				t.Errorf("jobContainer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package kube
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"scan_project/internal/model"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultErrorsTopN is the number of the most frequent errors kept in scan when config doesn't set it
+	defaultErrorsTopN = 10
+	// maxTrackedFingerprints limits number of distinct errors tracked per container, new errors over the limit are skipped
+	maxTrackedFingerprints = 500
+	maxErrorMessageLength  = 1000
+	maxErrorSampleLength   = 8 * 1024
+)
+
+// fingerprintReplacers normalise variable parts of error messages. Order matters: more specific patterns go first
+var fingerprintReplacers = []struct {
+	regexp      *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), "<ts>"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\b`), "<ts>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]*\d[0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*\b|\b[0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*\d[0-9a-fA-F]*\b`), "<hex>"},
+	{regexp.MustCompile(`\b\d+(?:\.\d+)?`), "<num>"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// errorFingerprints accumulates error log entries grouped by fingerprint
+type errorFingerprints map[string]model.ErrorFingerprint
+
+// add counts error entry. message is used for fingerprint and sample is the whole log entry
+func (ef errorFingerprints) add(message string, sample string, logTime time.Time) {
+	pattern := normaliseErrorMessage(message)
+	hash := sha1.Sum([]byte(pattern))
+	fingerprint := hex.EncodeToString(hash[:6])
+	errFingerprint, ok := ef[fingerprint]
+	if !ok {
+		if len(ef) >= maxTrackedFingerprints {
+			return
+		}
+		if len(sample) > maxErrorSampleLength {
+			sample = sample[:maxErrorSampleLength]
+		}
+		errFingerprint = model.ErrorFingerprint{
+			Fingerprint: fingerprint,
+			Pattern:     pattern,
+			FirstSeen:   logTime,
+			Sample:      sample,
+		}
+	}
+	errFingerprint.Count++
+	errFingerprint.LastSeen = logTime
+	ef[fingerprint] = errFingerprint
+}
+
+// top returns n most frequent errors
+func (ef errorFingerprints) top(n int) []model.ErrorFingerprint {
+	fingerprints := make([]model.ErrorFingerprint, 0, len(ef))
+	for _, errFingerprint := range ef {
+		fingerprints = append(fingerprints, errFingerprint)
+	}
+	return topErrorFingerprints(fingerprints, n)
+}
+
+func (ef errorFingerprints) copy() errorFingerprints {
+	efCopy := make(errorFingerprints, len(ef))
+	for fingerprint, errFingerprint := range ef {
+		efCopy[fingerprint] = errFingerprint
+	}
+	return efCopy
+}
+
+// MergeErrorFingerprints sums the same errors found in different scans and returns n most frequent of them.
+//
+//	n <= 0 means that all merged errors are returned
+func MergeErrorFingerprints(fingerprintsLists [][]model.ErrorFingerprint, n int) []model.ErrorFingerprint {
+	merged := make(errorFingerprints)
+	for _, fingerprints := range fingerprintsLists {
+		for _, errFingerprint := range fingerprints {
+			mergedFingerprint, ok := merged[errFingerprint.Fingerprint]
+			if !ok {
+				merged[errFingerprint.Fingerprint] = errFingerprint
+				continue
+			}
+			mergedFingerprint.Count += errFingerprint.Count
+			if errFingerprint.FirstSeen.Before(mergedFingerprint.FirstSeen) {
+				mergedFingerprint.FirstSeen = errFingerprint.FirstSeen
+				mergedFingerprint.Sample = errFingerprint.Sample
+			}
+			if errFingerprint.LastSeen.After(mergedFingerprint.LastSeen) {
+				mergedFingerprint.LastSeen = errFingerprint.LastSeen
+			}
+			merged[errFingerprint.Fingerprint] = mergedFingerprint
+		}
+	}
+	return merged.top(n)
+}
+
+// topErrorFingerprints sorts errors by count and last appearance and returns n first of them, n <= 0 means all
+func topErrorFingerprints(fingerprints []model.ErrorFingerprint, n int) []model.ErrorFingerprint {
+	sort.Slice(fingerprints, func(i, j int) bool {
+		if fingerprints[i].Count != fingerprints[j].Count {
+			return fingerprints[i].Count > fingerprints[j].Count
+		}
+		return fingerprints[i].LastSeen.After(fingerprints[j].LastSeen)
+	})
+	if n > 0 && len(fingerprints) > n {
+		fingerprints = fingerprints[:n]
+	}
+	return fingerprints
+}
+
+// normaliseErrorMessage replaces ids, numbers, uuids, ip addresses and timestamps in message with placeholders
+func normaliseErrorMessage(message string) string {
+	if len(message) > maxErrorMessageLength {
+		message = message[:maxErrorMessageLength]
+	}
+	for _, replacer := range fingerprintReplacers {
+		message = replacer.regexp.ReplaceAllString(message, replacer.replacement)
+	}
+	return strings.TrimSpace(message)
+}
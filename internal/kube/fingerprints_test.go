@@ -0,0 +1,164 @@
+package kube
+
+import (
+	"reflect"
+	"scan_project/internal/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormaliseErrorMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "number",
+			message: "user 123 not found",
+			want:    "user <num> not found",
+		},
+		{
+			name:    "float",
+			message: "retry in 3.5 seconds",
+			want:    "retry in <num> seconds",
+		},
+		{
+			name:    "uuid",
+			message: "request 550e8400-e29b-41d4-a716-446655440000 failed",
+			want:    "request <uuid> failed",
+		},
+		{
+			name:    "timestamp",
+			message: "timeout at 2023-11-09T22:25:47.531Z",
+			want:    "timeout at <ts>",
+		},
+		{
+			name:    "time",
+			message: "job started at 22:25:47,531 failed",
+			want:    "job started at <ts> failed",
+		},
+		{
+			name:    "ip with port",
+			message: "connect to 10.0.0.1:5432 refused",
+			want:    "connect to <ip> refused",
+		},
+		{
+			name:    "hex",
+			message: "nil pointer at 0xdeadbeef, commit a1b2c3d",
+			want:    "nil pointer at <hex>, commit <hex>",
+		},
+		{
+			name:    "words of hex letters are kept",
+			message: "cafe is closed",
+			want:    "cafe is closed",
+		},
+		{
+			name:    "spaces",
+			message: "  multiple   spaces\tand\ttabs ",
+			want:    "multiple spaces and tabs",
+		},
+		{
+			name:    "long message is truncated",
+			message: strings.Repeat("a", maxErrorMessageLength+10),
+			want:    strings.Repeat("a", maxErrorMessageLength),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normaliseErrorMessage(tt.message); got != tt.want {
+				t.Errorf("normaliseErrorMessage(%q) = %q, want %q", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorFingerprints(t *testing.T) {
+	base := time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+	type entry struct {
+		message string
+		minute  int
+	}
+	tests := []struct {
+		name    string
+		entries []entry
+		n       int
+		want    []model.ErrorFingerprint
+	}{
+		{
+			name: "messages differing by ids are grouped",
+			entries: []entry{
+				{message: "user 1 not found", minute: 0},
+				{message: "db is down", minute: 1},
+				{message: "user 2 not found", minute: 2},
+			},
+			want: []model.ErrorFingerprint{
+				{Pattern: "user <num> not found", Count: 2, FirstSeen: base, LastSeen: base.Add(2 * time.Minute),
+					Sample: "user 1 not found"},
+				{Pattern: "db is down", Count: 1, FirstSeen: base.Add(time.Minute), LastSeen: base.Add(time.Minute),
+					Sample: "db is down"},
+			},
+		},
+		{
+			name: "equal counts are sorted by last appearance",
+			entries: []entry{
+				{message: "first", minute: 0},
+				{message: "second", minute: 1},
+			},
+			want: []model.ErrorFingerprint{
+				{Pattern: "second", Count: 1, FirstSeen: base.Add(time.Minute), LastSeen: base.Add(time.Minute),
+					Sample: "second"},
+				{Pattern: "first", Count: 1, FirstSeen: base, LastSeen: base, Sample: "first"},
+			},
+		},
+		{
+			name: "top n",
+			entries: []entry{
+				{message: "first", minute: 0},
+				{message: "second", minute: 1},
+				{message: "first", minute: 2},
+			},
+			n: 1,
+			want: []model.ErrorFingerprint{
+				{Pattern: "first", Count: 2, FirstSeen: base, LastSeen: base.Add(2 * time.Minute), Sample: "first"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprints := make(errorFingerprints)
+			for _, e := range tt.entries {
+				fingerprints.add(e.message, e.message, base.Add(time.Duration(e.minute)*time.Minute))
+			}
+			got := fingerprints.top(tt.n)
+			for i := range got {
+				got[i].Fingerprint = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("top(%d) = %+v, want %+v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeErrorFingerprints(t *testing.T) {
+	base := time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+	got := MergeErrorFingerprints([][]model.ErrorFingerprint{
+		{
+			{Fingerprint: "a", Count: 2, FirstSeen: base.Add(time.Minute), LastSeen: base.Add(3 * time.Minute), Sample: "a1"},
+			{Fingerprint: "b", Count: 1, FirstSeen: base, LastSeen: base, Sample: "b1"},
+		},
+		{
+			{Fingerprint: "a", Count: 1, FirstSeen: base, LastSeen: base.Add(2 * time.Minute), Sample: "a2"},
+			{Fingerprint: "c", Count: 1, FirstSeen: base.Add(time.Minute), LastSeen: base.Add(time.Minute), Sample: "c2"},
+		},
+	}, 2)
+	want := []model.ErrorFingerprint{
+		{Fingerprint: "a", Count: 3, FirstSeen: base, LastSeen: base.Add(3 * time.Minute), Sample: "a2"},
+		{Fingerprint: "c", Count: 1, FirstSeen: base.Add(time.Minute), LastSeen: base.Add(time.Minute), Sample: "c2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeErrorFingerprints() = %+v, want %+v", got, want)
+	}
+}
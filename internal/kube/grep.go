@@ -0,0 +1,336 @@
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"scan_project/configuration"
+	"scan_project/internal/model"
+	"time"
+)
+
+const (
+	// defaultGrepRuleName is name of the rule made of jobs_grep_pattern when config has no grep rules
+	defaultGrepRuleName = "default"
+	// maxGrepContext limits number of context lines before and after grep match
+	maxGrepContext = 20
+)
+
+// grepRule is compiled model.GrepRule
+type grepRule struct {
+	model.GrepRule
+	pattern *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// ValidateGrepRules checks that rules have unique names, their patterns can be compiled, severities are known
+// and context lines are within limits
+func ValidateGrepRules(rules []model.GrepRule) error {
+	_, err := compileGrepRules(rules)
+	if err != nil {
+		return model.NewServerErrorByCode(model.InvalidGrepRules)
+	}
+	return nil
+}
+
+func compileGrepRules(rules []model.GrepRule) ([]grepRule, error) {
+	compiled := make([]grepRule, 0, len(rules))
+	names := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("grep rule name is empty")
+		}
+		if _, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("grep rule %s is duplicated", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+		switch rule.Severity {
+		case "":
+			rule.Severity = model.Error
+		case model.Trace, model.Debug, model.Info, model.Warning, model.Error, model.Fatal:
+		default:
+			return nil, fmt.Errorf("unknown severity %s of grep rule %s", rule.Severity, rule.Name)
+		}
+		if rule.ContextBefore < 0 || rule.ContextBefore > maxGrepContext ||
+			rule.ContextAfter < 0 || rule.ContextAfter > maxGrepContext {
+			return nil, fmt.Errorf("context lines of grep rule %s must be from 0 to %d", rule.Name, maxGrepContext)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil || rule.Pattern == "" {
+			return nil, fmt.Errorf("invalid pattern of grep rule %s", rule.Name)
+		}
+		compiledRule := grepRule{GrepRule: rule, pattern: pattern}
+		if rule.ExcludePattern != "" {
+			compiledRule.exclude, err = regexp.Compile(rule.ExcludePattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclude pattern of grep rule %s", rule.Name)
+			}
+		}
+		compiled = append(compiled, compiledRule)
+	}
+	return compiled, nil
+}
+
+// grepRuleSet is compiled effective grep rules of namespace with refs and version recorded in job scans
+type grepRuleSet struct {
+	rules   []grepRule
+	refs    []model.GrepRuleRef
+	version string
+}
+
+// newGrepRuleSet compiles rules. Version is hash of all rules fields, so it's changed by any change
+// which can change grep result
+func newGrepRuleSet(rules []model.GrepRule) (*grepRuleSet, error) {
+	compiled, err := compileGrepRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	set := &grepRuleSet{
+		rules: compiled,
+		refs:  make([]model.GrepRuleRef, 0, len(compiled)),
+	}
+	hash := sha256.New()
+	for _, rule := range compiled {
+		set.refs = append(set.refs, model.GrepRuleRef{
+			Name:           rule.Name,
+			Pattern:        rule.Pattern,
+			ExcludePattern: rule.ExcludePattern,
+		})
+		ruleBytes, err := json.Marshal(rule.GrepRule)
+		if err != nil {
+			return nil, err
+		}
+		hash.Write(ruleBytes)
+	}
+	set.version = hex.EncodeToString(hash.Sum(nil))[:16]
+	return set, nil
+}
+
+// grepJobLog greps stored job log. Lines of truncated log are numbered as lines of stored log including truncation marker
+func (gs *grepRuleSet) grepJobLog(logBytes []byte, multiline multilineConfig) (*logGrep, error) {
+	grep := newLogGrep(gs.rules, multiline)
+	scanner := bufio.NewScanner(bytes.NewReader(logBytes))
+	scanner.Buffer(nil, maxLogLineSize)
+	for scanner.Scan() {
+		grep.addLine(scanner.Bytes())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	grep.finish()
+	return grep, nil
+}
+
+// setGrepResult replaces grep fields of job scan with result of grep by rule set
+func (gs *grepRuleSet) setGrepResult(jobScan *model.JobScan, grep *logGrep) {
+	jobScan.GrepRules = gs.refs
+	jobScan.GrepVersion = gs.version
+	jobScan.GrepLog = grep.matchedLog
+	jobScan.GrepMatches = grep.matches
+}
+
+// newGlobalGrepRules converts config grep rules. Config without rules has the single rule made of jobs_grep_pattern
+func newGlobalGrepRules(cfg *configuration.Config) []model.GrepRule {
+	if len(cfg.JobsGrepRules) == 0 {
+		return []model.GrepRule{{
+			Name:     defaultGrepRuleName,
+			Pattern:  cfg.JobsGrepPattern,
+			Severity: model.Error,
+		}}
+	}
+	rules := make([]model.GrepRule, 0, len(cfg.JobsGrepRules))
+	for _, rule := range cfg.JobsGrepRules {
+		rules = append(rules, model.GrepRule{
+			Name:           rule.Name,
+			Pattern:        rule.Pattern,
+			ExcludePattern: rule.ExcludePattern,
+			Severity:       rule.Severity,
+			Description:    rule.Description,
+			ContextBefore:  rule.ContextBefore,
+			ContextAfter:   rule.ContextAfter,
+		})
+	}
+	return rules
+}
+
+// effectiveGrepRules merges grep rules of levels global -> cluster -> namespace. Rule of lower level replaces
+// the upper level rule with the same name
+func effectiveGrepRules(global []model.GrepRule, cluster model.Cluster, namespace model.Namespace) []model.GrepRule {
+	rules := make([]model.GrepRule, 0, len(global)+len(cluster.GrepRules)+len(namespace.GrepRules))
+	indexes := make(map[string]int)
+	for _, levelRules := range [][]model.GrepRule{global, cluster.GrepRules, namespace.GrepRules} {
+		for _, rule := range levelRules {
+			if i, ok := indexes[rule.Name]; ok {
+				rules[i] = rule
+				continue
+			}
+			indexes[rule.Name] = len(rules)
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// GrepRules returns global grep rules of jobs logs
+func (ks *KubeScanner) GrepRules() []model.GrepRule {
+	return append(make([]model.GrepRule, 0, len(ks.grepRules)), ks.grepRules...)
+}
+
+// RegrepJobScan greps stored log of the job by the current grep rules of namespace without reading it from kubernetes.
+//
+//	Job scan with grep fields replaced by the new result is returned, saved scans aren't changed
+func (ks *KubeScanner) RegrepJobScan(clusterName string, namespaceName string, jobScan model.JobScan) (*model.JobScan, error) {
+	cluster, err := ks.storage.GetClusterByName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := ks.storage.GetNamespace(clusterName, namespaceName)
+	if err != nil {
+		return nil, err
+	}
+	ruleSet, err := newGrepRuleSet(effectiveGrepRules(ks.grepRules, *cluster, *namespace))
+	if err != nil {
+		return nil, err
+	}
+	err = ks.regrepJobScan(clusterName, namespaceName, ruleSet, &jobScan)
+	if err != nil {
+		return nil, err
+	}
+	return &jobScan, nil
+}
+
+func (ks *KubeScanner) regrepJobScan(clusterName string, namespace string, ruleSet *grepRuleSet, jobScan *model.JobScan) error {
+	jobLog, err := ks.storage.GetJobLog(clusterName, namespace, jobScan.JobName)
+	if err != nil {
+		return err
+	}
+	logBytes, err := ReadJobLog(jobLog)
+	if err != nil {
+		return err
+	}
+	grep, err := ruleSet.grepJobLog(logBytes, ks.multiline)
+	if err != nil {
+		return err
+	}
+	ruleSet.setGrepResult(jobScan, grep)
+	return nil
+}
+
+// pendingMatch is grep match which waits for its after context lines
+type pendingMatch struct {
+	index int // index of match in logGrep matches
+	after int // number of after context lines which are still needed
+}
+
+// logGrep matches log events against grep rules and collects matches with their line numbers and context lines.
+//
+//	Log events are assembled from physical lines by multiline assembler, matched event is returned whole
+type logGrep struct {
+	rules      []grepRule
+	events     *multilineAssembler
+	maxBefore  int
+	recent     []string // the last maxBefore lines
+	lineNumber int
+	// Line number and before context of the event which is being assembled
+	eventLine   int
+	eventBefore []string
+	pending     []pendingMatch
+	matches     []model.GrepMatch
+	matchedLog  []string
+}
+
+func newLogGrep(rules []grepRule, multiline multilineConfig) *logGrep {
+	lg := &logGrep{
+		rules:      rules,
+		matches:    make([]model.GrepMatch, 0),
+		matchedLog: make([]string, 0),
+	}
+	for _, rule := range rules {
+		if rule.ContextBefore > lg.maxBefore {
+			lg.maxBefore = rule.ContextBefore
+		}
+	}
+	lg.events = newMultilineAssembler(multiline, lg.matchEvent)
+	return lg
+}
+
+// addLine passes the next physical log line
+func (lg *logGrep) addLine(line []byte) {
+	lg.lineNumber++
+	current := lg.events.current
+	lg.events.add(time.Time{}, line)
+	text := string(line)
+	// The line is after context of matches of all previous events
+	for i := 0; i < len(lg.pending); {
+		match := &lg.matches[lg.pending[i].index]
+		match.After = append(match.After, text)
+		lg.pending[i].after--
+		if lg.pending[i].after == 0 {
+			lg.pending = append(lg.pending[:i], lg.pending[i+1:]...)
+			continue
+		}
+		i++
+	}
+	if lg.events.current != current {
+		lg.eventLine = lg.lineNumber
+		lg.eventBefore = append(make([]string, 0, len(lg.recent)), lg.recent...)
+	}
+	if lg.maxBefore > 0 {
+		if len(lg.recent) == lg.maxBefore {
+			lg.recent = lg.recent[1:]
+		}
+		lg.recent = append(lg.recent, text)
+	}
+}
+
+// finish matches the last log event, should be called when log is over
+func (lg *logGrep) finish() {
+	lg.events.flush()
+	lg.pending = nil
+}
+
+func (lg *logGrep) matchEvent(event *logEvent) {
+	matched := false
+	for _, rule := range lg.rules {
+		if !rule.matches(event) {
+			continue
+		}
+		before := lg.eventBefore
+		if len(before) > rule.ContextBefore {
+			before = before[len(before)-rule.ContextBefore:]
+		}
+		lg.matches = append(lg.matches, model.GrepMatch{
+			Rule:     rule.Name,
+			Severity: rule.Severity,
+			Line:     lg.eventLine,
+			Text:     event.text(),
+			Before:   append(make([]string, 0, len(before)), before...),
+			After:    make([]string, 0, rule.ContextAfter),
+		})
+		if rule.ContextAfter > 0 {
+			lg.pending = append(lg.pending, pendingMatch{index: len(lg.matches) - 1, after: rule.ContextAfter})
+		}
+		matched = true
+	}
+	if matched {
+		lg.matchedLog = append(lg.matchedLog, event.text())
+	}
+}
+
+// matches checks if any event line matches rule pattern and none of them matches exclude pattern
+func (gr *grepRule) matches(event *logEvent) bool {
+	matched := false
+	for _, line := range event.lines {
+		if gr.exclude != nil && gr.exclude.MatchString(line) {
+			return false
+		}
+		if !matched && gr.pattern.MatchString(line) {
+			matched = true
+		}
+	}
+	return matched
+}
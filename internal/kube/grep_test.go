@@ -0,0 +1,206 @@
+package kube
+
+import (
+	"reflect"
+	"scan_project/internal/model"
+	"strings"
+	"testing"
+)
+
+func TestGrepJobLog(t *testing.T) {
+	tests := []struct {
+		name           string
+		rules          []model.GrepRule
+		lines          []string
+		wantMatches    []model.GrepMatch
+		wantMatchedLog []string
+	}{
+		{
+			name: "context lines and multiline event",
+			rules: []model.GrepRule{
+				{Name: "errors", Pattern: "ERROR", ExcludePattern: "expected", ContextBefore: 1, ContextAfter: 2},
+				{Name: "warnings", Pattern: "WARN", Severity: model.Warning},
+			},
+			lines: []string{
+				"start",
+				"INFO step 1",
+				"ERROR failed to connect",
+				"\tat com.example.Db.connect(Db.java:10)",
+				"INFO retry",
+				"WARN slow",
+				"ERROR expected error",
+				"done",
+			},
+			wantMatches: []model.GrepMatch{
+				{
+					Rule:     "errors",
+					Severity: model.Error,
+					Line:     3,
+					Text:     "ERROR failed to connect\n\tat com.example.Db.connect(Db.java:10)",
+					Before:   []string{"INFO step 1"},
+					After:    []string{"INFO retry", "WARN slow"},
+				},
+				{
+					Rule:     "warnings",
+					Severity: model.Warning,
+					Line:     6,
+					Text:     "WARN slow",
+					Before:   []string{},
+					After:    []string{},
+				},
+			},
+			wantMatchedLog: []string{"ERROR failed to connect\n\tat com.example.Db.connect(Db.java:10)", "WARN slow"},
+		},
+		{
+			name: "event matched by several rules is logged once",
+			rules: []model.GrepRule{
+				{Name: "errors", Pattern: "(?i)error", ContextBefore: 3, ContextAfter: 5},
+				{Name: "timeouts", Pattern: "timeout", ContextAfter: 1},
+			},
+			lines: []string{
+				"INFO started",
+				"ERROR timeout",
+				"INFO finished",
+			},
+			wantMatches: []model.GrepMatch{
+				{
+					Rule:     "errors",
+					Severity: model.Error,
+					Line:     2,
+					Text:     "ERROR timeout",
+					Before:   []string{"INFO started"},
+					After:    []string{"INFO finished"},
+				},
+				{
+					Rule:     "timeouts",
+					Severity: model.Error,
+					Line:     2,
+					Text:     "ERROR timeout",
+					Before:   []string{},
+					After:    []string{"INFO finished"},
+				},
+			},
+			wantMatchedLog: []string{"ERROR timeout"},
+		},
+		{
+			name:           "nothing matched",
+			rules:          []model.GrepRule{{Name: "errors", Pattern: "ERROR"}},
+			lines:          []string{"INFO started", "INFO finished"},
+			wantMatches:    []model.GrepMatch{},
+			wantMatchedLog: []string{},
+		},
+	}
+	multiline, err := newMultilineConfig([]string{"java"}, "")
+	if err != nil {
+		t.Fatalf("newMultilineConfig() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ruleSet, err := newGrepRuleSet(tt.rules)
+			if err != nil {
+				t.Fatalf("newGrepRuleSet() error = %v", err)
+			}
+			grep, err := ruleSet.grepJobLog([]byte(strings.Join(tt.lines, "\n")+"\n"), multiline)
+			if err != nil {
+				t.Fatalf("grepJobLog() error = %v", err)
+			}
+			if !reflect.DeepEqual(grep.matches, tt.wantMatches) {
+				t.Errorf("matches = %+v, want %+v", grep.matches, tt.wantMatches)
+			}
+			if !reflect.DeepEqual(grep.matchedLog, tt.wantMatchedLog) {
+				t.Errorf("matched log = %q, want %q", grep.matchedLog, tt.wantMatchedLog)
+			}
+		})
+	}
+}
+
+func TestCompileGrepRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []model.GrepRule
+		wantErr bool
+	}{
+		{
+			name:  "valid rules",
+			rules: []model.GrepRule{{Name: "a", Pattern: "a"}, {Name: "b", Pattern: "b", Severity: model.Warning}},
+		},
+		{
+			name:    "empty name",
+			rules:   []model.GrepRule{{Pattern: "a"}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicated name",
+			rules:   []model.GrepRule{{Name: "a", Pattern: "a"}, {Name: "a", Pattern: "b"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown severity",
+			rules:   []model.GrepRule{{Name: "a", Pattern: "a", Severity: "critical"}},
+			wantErr: true,
+		},
+		{
+			name:    "too many context lines",
+			rules:   []model.GrepRule{{Name: "a", Pattern: "a", ContextAfter: maxGrepContext + 1}},
+			wantErr: true,
+		},
+		{
+			name:    "empty pattern",
+			rules:   []model.GrepRule{{Name: "a"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid exclude pattern",
+			rules:   []model.GrepRule{{Name: "a", Pattern: "a", ExcludePattern: "("}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileGrepRules(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileGrepRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGrepRuleSetVersion(t *testing.T) {
+	rules := []model.GrepRule{{Name: "errors", Pattern: "ERROR"}}
+	changed := []model.GrepRule{{Name: "errors", Pattern: "ERROR", ContextAfter: 1}}
+	first, err := newGrepRuleSet(rules)
+	if err != nil {
+		t.Fatalf("newGrepRuleSet() error = %v", err)
+	}
+	second, err := newGrepRuleSet(rules)
+	if err != nil {
+		t.Fatalf("newGrepRuleSet() error = %v", err)
+	}
+	third, err := newGrepRuleSet(changed)
+	if err != nil {
+		t.Fatalf("newGrepRuleSet() error = %v", err)
+	}
+	if first.version != second.version {
+		t.Errorf("versions of the same rules differ: %s, %s", first.version, second.version)
+	}
+	if first.version == third.version {
+		t.Errorf("version isn't changed by context lines change")
+	}
+}
+
+func TestEffectiveGrepRules(t *testing.T) {
+	global := []model.GrepRule{{Name: "errors", Pattern: "ERROR"}, {Name: "panics", Pattern: "panic"}}
+	cluster := model.Cluster{GrepRules: []model.GrepRule{{Name: "errors", Pattern: "(?i)error"}}}
+	namespace := model.Namespace{GrepRules: []model.GrepRule{
+		{Name: "panics", Pattern: "panic:"},
+		{Name: "timeouts", Pattern: "timeout"},
+	}}
+	want := []model.GrepRule{
+		{Name: "errors", Pattern: "(?i)error"},
+		{Name: "panics", Pattern: "panic:"},
+		{Name: "timeouts", Pattern: "timeout"},
+	}
+	if got := effectiveGrepRules(global, cluster, namespace); !reflect.DeepEqual(got, want) {
+		t.Errorf("effectiveGrepRules() = %+v, want %+v", got, want)
+	}
+}
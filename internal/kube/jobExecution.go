@@ -0,0 +1,100 @@
+package kube
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"scan_project/internal/model"
+)
+
+// jobExecution describes run of the completed job pod and state of the Job which owns it.
+//
+//	Job state changes while Job retries its pods, so execution isn't kept in checkpoints and is built on every scan
+func jobExecution(pod *v1.Pod, container string, job *batchv1.Job) model.JobExecution {
+	execution := model.JobExecution{
+		Status: model.JobRunning,
+	}
+	switch pod.Status.Phase {
+	case v1.PodSucceeded:
+		execution.Status = model.JobSucceeded
+	case v1.PodFailed:
+		execution.Status = model.JobFailed
+	}
+	if pod.Status.StartTime != nil {
+		execution.StartTime = pod.Status.StartTime.Time
+	}
+	// Pod is completed when its last container is terminated
+	var failed *v1.ContainerStateTerminated
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if terminated == nil {
+			continue
+		}
+		if terminated.FinishedAt.Time.After(execution.CompletionTime) {
+			execution.CompletionTime = terminated.FinishedAt.Time
+		}
+		if status.Name == container {
+			exitCode := int(terminated.ExitCode)
+			execution.ExitCode = &exitCode
+		}
+		if terminated.ExitCode != 0 && (failed == nil || status.Name == container) {
+			failed = terminated
+		}
+	}
+	if !execution.StartTime.IsZero() && !execution.CompletionTime.IsZero() {
+		execution.Duration = execution.CompletionTime.Sub(execution.StartTime)
+	}
+	// Pod reason is set when pod is failed by kubelet, e.g. DeadlineExceeded or Evicted
+	switch {
+	case pod.Status.Reason != "":
+		execution.FailureReason = pod.Status.Reason
+		execution.FailureMessage = pod.Status.Message
+	case failed != nil:
+		execution.FailureReason = failed.Reason
+		execution.FailureMessage = failed.Message
+	}
+	if job != nil {
+		execution.Job = jobRun(job)
+	}
+	return execution
+}
+
+// jobRun describes state of batch/v1 Job by its status and conditions
+func jobRun(job *batchv1.Job) *model.JobRun {
+	run := &model.JobRun{
+		Name:      job.Name,
+		Status:    model.JobRunning,
+		Active:    int(job.Status.Active),
+		Succeeded: int(job.Status.Succeeded),
+		Failed:    int(job.Status.Failed),
+	}
+	run.Attempts = run.Active + run.Succeeded + run.Failed
+	if job.Spec.BackoffLimit != nil {
+		run.BackoffLimit = int(*job.Spec.BackoffLimit)
+	}
+	if job.Status.StartTime != nil {
+		run.StartTime = job.Status.StartTime.Time
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			run.Status = model.JobSucceeded
+			run.CompletionTime = condition.LastTransitionTime.Time
+		case batchv1.JobFailed:
+			run.Status = model.JobFailed
+			run.CompletionTime = condition.LastTransitionTime.Time
+			run.FailureReason = condition.Reason
+			run.FailureMessage = condition.Message
+		}
+	}
+	// Completion time is set for succeeded Jobs only
+	if job.Status.CompletionTime != nil {
+		run.CompletionTime = job.Status.CompletionTime.Time
+	}
+	if run.Status != model.JobRunning && !run.StartTime.IsZero() && !run.CompletionTime.IsZero() {
+		run.Duration = run.CompletionTime.Sub(run.StartTime)
+	}
+	return run
+}
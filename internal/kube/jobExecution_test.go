@@ -0,0 +1,210 @@
+package kube
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"scan_project/internal/model"
+	"testing"
+	"time"
+)
+
+func TestJobExecution(t *testing.T) {
+	start := time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+	finish := start.Add(time.Minute)
+	startTime := metav1.NewTime(start)
+	terminated := func(name string, exitCode int32, reason string) v1.ContainerStatus {
+		return v1.ContainerStatus{
+			Name: name,
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+				ExitCode:   exitCode,
+				Reason:     reason,
+				Message:    reason + " message",
+				FinishedAt: metav1.NewTime(finish),
+			}},
+		}
+	}
+	exitCode := func(code int) *int {
+		return &code
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job"}}
+	tests := []struct {
+		name      string
+		pod       v1.Pod
+		container string
+		job       *batchv1.Job
+		want      model.JobExecution
+	}{
+		{
+			name: "succeeded",
+			pod: v1.Pod{Status: v1.PodStatus{
+				Phase:             v1.PodSucceeded,
+				StartTime:         &startTime,
+				ContainerStatuses: []v1.ContainerStatus{terminated("main", 0, "Completed")},
+			}},
+			container: "main",
+			job:       job,
+			want: model.JobExecution{
+				Status:         model.JobSucceeded,
+				StartTime:      start,
+				CompletionTime: finish,
+				Duration:       time.Minute,
+				ExitCode:       exitCode(0),
+				Job:            jobRun(job),
+			},
+		},
+		{
+			name: "failure of scanned container is preferred",
+			pod: v1.Pod{Status: v1.PodStatus{
+				Phase:     v1.PodFailed,
+				StartTime: &startTime,
+				ContainerStatuses: []v1.ContainerStatus{
+					terminated("sidecar", 1, "Error"),
+					terminated("main", 137, "OOMKilled"),
+				},
+			}},
+			container: "main",
+			want: model.JobExecution{
+				Status:         model.JobFailed,
+				StartTime:      start,
+				CompletionTime: finish,
+				Duration:       time.Minute,
+				ExitCode:       exitCode(137),
+				FailureReason:  "OOMKilled",
+				FailureMessage: "OOMKilled message",
+			},
+		},
+		{
+			name: "failed by kubelet",
+			pod: v1.Pod{Status: v1.PodStatus{
+				Phase:   v1.PodFailed,
+				Reason:  "DeadlineExceeded",
+				Message: "Pod was active on the node longer than the specified deadline",
+			}},
+			container: "main",
+			want: model.JobExecution{
+				Status:         model.JobFailed,
+				FailureReason:  "DeadlineExceeded",
+				FailureMessage: "Pod was active on the node longer than the specified deadline",
+			},
+		},
+		{
+			name: "running",
+			pod: v1.Pod{Status: v1.PodStatus{
+				Phase:     v1.PodRunning,
+				StartTime: &startTime,
+			}},
+			container: "main",
+			want: model.JobExecution{
+				Status:    model.JobRunning,
+				StartTime: start,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jobExecution(&tt.pod, tt.container, tt.job); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jobExecution() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobRun(t *testing.T) {
+	start := time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+	startTime := metav1.NewTime(start)
+	completionTime := metav1.NewTime(start.Add(2 * time.Minute))
+	backoffLimit := int32(6)
+	tests := []struct {
+		name string
+		job  batchv1.Job
+		want *model.JobRun
+	}{
+		{
+			name: "succeeded after retries",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "job"},
+				Spec:       batchv1.JobSpec{BackoffLimit: &backoffLimit},
+				Status: batchv1.JobStatus{
+					StartTime:      &startTime,
+					CompletionTime: &completionTime,
+					Succeeded:      1,
+					Failed:         2,
+					Conditions: []batchv1.JobCondition{{
+						Type:               batchv1.JobComplete,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: metav1.NewTime(start.Add(3 * time.Minute)),
+					}},
+				},
+			},
+			want: &model.JobRun{
+				Name:           "job",
+				Status:         model.JobSucceeded,
+				StartTime:      start,
+				CompletionTime: start.Add(2 * time.Minute),
+				Duration:       2 * time.Minute,
+				Attempts:       3,
+				Succeeded:      1,
+				Failed:         2,
+				BackoffLimit:   6,
+			},
+		},
+		{
+			name: "failed",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "job"},
+				Status: batchv1.JobStatus{
+					StartTime: &startTime,
+					Failed:    1,
+					Conditions: []batchv1.JobCondition{{
+						Type:               batchv1.JobFailed,
+						Status:             v1.ConditionTrue,
+						LastTransitionTime: metav1.NewTime(start.Add(time.Minute)),
+						Reason:             "BackoffLimitExceeded",
+						Message:            "Job has reached the specified backoff limit",
+					}},
+				},
+			},
+			want: &model.JobRun{
+				Name:           "job",
+				Status:         model.JobFailed,
+				StartTime:      start,
+				CompletionTime: start.Add(time.Minute),
+				Duration:       time.Minute,
+				Attempts:       1,
+				Failed:         1,
+				FailureReason:  "BackoffLimitExceeded",
+				FailureMessage: "Job has reached the specified backoff limit",
+			},
+		},
+		{
+			name: "running with false condition",
+			job: batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "job"},
+				Status: batchv1.JobStatus{
+					StartTime: &startTime,
+					Active:    1,
+					Conditions: []batchv1.JobCondition{{
+						Type:   batchv1.JobFailed,
+						Status: v1.ConditionFalse,
+					}},
+				},
+			},
+			want: &model.JobRun{
+				Name:      "job",
+				Status:    model.JobRunning,
+				StartTime: start,
+				Attempts:  1,
+				Active:    1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jobRun(&tt.job); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jobRun() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
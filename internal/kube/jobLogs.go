@@ -0,0 +1,95 @@
+package kube
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"scan_project/internal/model"
+)
+
+// defaultJobLogMaxSize limits stored job log when config doesn't set it
+const defaultJobLogMaxSize = 10 << 20
+
+// jobLogBuffer collects job log keeping its head and tail when log exceeds maxSize.
+//
+//	The first half of maxSize is taken by the log beginning and the second half by the log end,
+//	the cut middle is replaced with truncation marker. Lines are never split
+type jobLogBuffer struct {
+	maxSize int
+	head    bytes.Buffer
+	tail    [][]byte
+	tailLen int
+	size    int64 // size of the whole log
+	skipped int64 // size of lines which were cut
+}
+
+func newJobLogBuffer(maxSize int) *jobLogBuffer {
+	return &jobLogBuffer{maxSize: maxSize}
+}
+
+func (b *jobLogBuffer) addLine(line []byte) {
+	lineLen := len(line) + 1
+	b.size += int64(lineLen)
+	if b.tail == nil && b.head.Len()+lineLen <= b.maxSize/2 {
+		b.head.Write(line)
+		b.head.WriteByte('\n')
+		return
+	}
+	tailLine := make([]byte, 0, lineLen)
+	tailLine = append(append(tailLine, line...), '\n')
+	b.tail = append(b.tail, tailLine)
+	b.tailLen += lineLen
+	for b.tailLen > b.maxSize-b.maxSize/2 && len(b.tail) != 0 {
+		b.tailLen -= len(b.tail[0])
+		b.skipped += int64(len(b.tail[0]))
+		b.tail = b.tail[1:]
+	}
+}
+
+// jobLog compresses collected log into model.JobLog
+func (b *jobLogBuffer) jobLog(jobName string) (*model.JobLog, error) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write(b.head.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	storedSize := int64(b.head.Len())
+	if b.skipped != 0 {
+		marker := fmt.Sprintf("... %d bytes of log are truncated ...\n", b.skipped)
+		_, err = gz.Write([]byte(marker))
+		if err != nil {
+			return nil, err
+		}
+		storedSize += int64(len(marker))
+	}
+	for _, line := range b.tail {
+		_, err = gz.Write(line)
+		if err != nil {
+			return nil, err
+		}
+		storedSize += int64(len(line))
+	}
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+	return &model.JobLog{
+		JobName:      jobName,
+		Size:         storedSize,
+		OriginalSize: b.size,
+		Truncated:    b.skipped != 0,
+		Compressed:   compressed.Bytes(),
+	}, nil
+}
+
+// ReadJobLog decompresses stored job log
+func ReadJobLog(jobLog *model.JobLog) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(jobLog.Compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
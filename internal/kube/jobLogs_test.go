@@ -0,0 +1,67 @@
+package kube
+
+import "testing"
+
+func TestJobLogBuffer(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxSize          int
+		lines            []string
+		want             string
+		wantOriginalSize int64
+		wantTruncated    bool
+	}{
+		{
+			name:             "log fits",
+			maxSize:          20,
+			lines:            []string{"aaa", "bbb"},
+			want:             "aaa\nbbb\n",
+			wantOriginalSize: 8,
+		},
+		{
+			name:             "middle is cut",
+			maxSize:          20,
+			lines:            []string{"line1", "line2", "line3", "line4", "line5", "line6"},
+			want:             "line1\n... 24 bytes of log are truncated ...\nline6\n",
+			wantOriginalSize: 36,
+			wantTruncated:    true,
+		},
+		{
+			name:             "line longer than tail",
+			maxSize:          10,
+			lines:            []string{"0123456789"},
+			want:             "... 11 bytes of log are truncated ...\n",
+			wantOriginalSize: 11,
+			wantTruncated:    true,
+		},
+		{
+			name:    "empty log",
+			maxSize: 10,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := newJobLogBuffer(tt.maxSize)
+			for _, line := range tt.lines {
+				buffer.addLine([]byte(line))
+			}
+			jobLog, err := buffer.jobLog("job")
+			if err != nil {
+				t.Fatalf("jobLog() error = %v", err)
+			}
+			log, err := ReadJobLog(jobLog)
+			if err != nil {
+				t.Fatalf("ReadJobLog() error = %v", err)
+			}
+			if string(log) != tt.want {
+				t.Errorf("log = %q, want %q", log, tt.want)
+			}
+			if jobLog.Size != int64(len(tt.want)) || jobLog.OriginalSize != tt.wantOriginalSize ||
+				jobLog.Truncated != tt.wantTruncated {
+				t.Errorf("size = %d, original size = %d, truncated = %v, want %d, %d, %v", jobLog.Size,
+					jobLog.OriginalSize, jobLog.Truncated, len(tt.want), tt.wantOriginalSize, tt.wantTruncated)
+			}
+		})
+	}
+}
@@ -5,70 +5,124 @@ import (
 	"fmt"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"regexp"
+	"k8s.io/apimachinery/pkg/types"
 	"scan_project/configuration"
 	"scan_project/internal/model"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// defaultScanTimeout limits single namespace scan when config doesn't set it
+const defaultScanTimeout = 5 * time.Minute
+
+// maxFailedPodsErrors is the number of pods errors listed in scan status of partially failed scan
+const maxFailedPodsErrors = 5
+
 type KubeScanner struct {
-	storage           StorageI
-	kubernetesTimeout *int
-	logger            *logrus.Entry
-	stopChan          chan struct{}
-	startProcessWg    sync.WaitGroup
-	jobsRegexp        *regexp.Regexp
-	isRunning         bool
-	servicesRegexp    *regexp.Regexp
+	storage        StorageI
+	logger         *logrus.Entry
+	mutex          sync.Mutex
+	ctx            context.Context    // context of running scanner, on-demand scans are run with it
+	cancel         context.CancelFunc // cancels running scans, nil when scanner isn't running
+	startProcessWg sync.WaitGroup
+	grepRules      []model.GrepRule // global grep rules of jobs logs
+	jobLogMaxSize  int
+	scanTimeout    time.Duration
+	checkpoints    *checkpoints
+	levels         *levelNormalizer
+	errorsTopN     int
+	multiline      multilineConfig
+	clients        *clusterClients
+	pool           *scanPool
+	tasks          *scanTasks
+	scheduler      *scheduler
+	discoveries    *discoveries
+	namespaceLocks sync.Map // cluster/namespace -> *sync.Mutex
 }
 
 func NewKubeScanner(storage StorageI, cfg *configuration.Config, logger *logrus.Entry) *KubeScanner {
+	errorsTopN := cfg.ErrorsTopN
+	if errorsTopN <= 0 {
+		errorsTopN = defaultErrorsTopN
+	}
+	scanWorkers := cfg.System.Kubernetes.Workers
+	if scanWorkers <= 0 {
+		scanWorkers = defaultScanWorkers
+	}
+	jobLogMaxSize := cfg.JobLogMaxSize
+	if jobLogMaxSize <= 0 {
+		jobLogMaxSize = defaultJobLogMaxSize
+	}
+	scanTimeout := time.Duration(cfg.ScanTimeout) * time.Second
+	if scanTimeout <= 0 {
+		scanTimeout = defaultScanTimeout
+	}
+	clients := newClusterClients(time.Duration(*cfg.System.Kubernetes.Timeout)*time.Second,
+		cfg.System.Kubernetes.QPS, cfg.System.Kubernetes.Burst, cfg.System.Kubernetes.CredentialsDir, logger)
+	multiline, err := newMultilineConfig(cfg.Multiline.Presets, cfg.Multiline.StartPattern)
+	if err != nil {
+		logger.
+			WithField("error", err).
+			Error("Failed to parse multiline config, default presets will be used")
+		multiline, _ = newMultilineConfig(nil, "")
+	}
+	grepRules := newGlobalGrepRules(cfg)
+	if _, err = compileGrepRules(grepRules); err != nil {
+		logger.
+			WithField("error", err).
+			Error("Failed to parse jobs grep rules, jobs_grep_pattern will be used")
+		grepRules = newGlobalGrepRules(&configuration.Config{JobsGrepPattern: cfg.JobsGrepPattern})
+	}
 	return &KubeScanner{
-		storage:           storage,
-		kubernetesTimeout: cfg.System.Kubernetes.Timeout,
-		jobsRegexp:        regexp.MustCompile(cfg.JobsGrepPattern),
-		startProcessWg:    sync.WaitGroup{},
-		logger:            logger,
-		stopChan:          make(chan struct{}, 1),
-		isRunning:         false,
-		servicesRegexp:    regexp.MustCompile("\"level\":\"\\w+\""),
+		storage:        storage,
+		grepRules:      grepRules,
+		jobLogMaxSize:  jobLogMaxSize,
+		startProcessWg: sync.WaitGroup{},
+		logger:         logger,
+		scanTimeout:    scanTimeout,
+		checkpoints:    newCheckpoints(),
+		levels:         newLevelNormalizer(cfg.LogLevels, logger),
+		errorsTopN:     errorsTopN,
+		multiline:      multiline,
+		clients:        clients,
+		pool:           newScanPool(scanWorkers, logger),
+		tasks:          newScanTasks(),
+		scheduler:      newScheduler(),
+		discoveries:    newDiscoveries(),
 	}
 }
 
-// Start will do scan all kubernetes clusters gotten from ClusterDAOI every intervalSec seconds and save result in ScansDAOI
+// Start will scan namespaces of all kubernetes clusters gotten from ClusterDAOI by their schedules and save result in ScansDAOI
 //
-// The first scan will take place immediately
-func (ks *KubeScanner) Start(intervalSec int) error {
-	if ks.isRunning {
+// Namespaces without own or cluster schedule are scanned every intervalSec seconds. The first scan of every namespace
+// will take place immediately. Scans are stopped when ctx is cancelled or Shutdown is called
+func (ks *KubeScanner) Start(ctx context.Context, intervalSec int) error {
+	ks.mutex.Lock()
+	if ks.cancel != nil {
+		ks.mutex.Unlock()
 		return fmt.Errorf("kube-scanner are already running")
 	}
-	ks.isRunning = true
+	ctx, ks.cancel = context.WithCancel(ctx)
+	ks.ctx = ctx
 	ks.startProcessWg.Add(1)
+	ks.mutex.Unlock()
 	defer ks.startProcessWg.Done()
-	ks.ScanAll()
-	ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
-	for {
-		select {
-		case <-ks.stopChan:
-			ticker.Stop()
-			return nil
-		case <-ticker.C:
-			ks.ScanAll()
-			ticker.Reset(time.Duration(intervalSec) * time.Second)
-		}
-	}
+	ks.runScheduler(ctx, time.Duration(intervalSec)*time.Second)
+	return nil
 }
 
+// Shutdown cancels running scans and waits until they are finished or ctx is done
 func (ks *KubeScanner) Shutdown(ctx context.Context) error {
-	if !ks.isRunning {
+	ks.mutex.Lock()
+	if ks.cancel == nil {
+		ks.mutex.Unlock()
 		return fmt.Errorf("kube-scanner is already down")
 	}
-	ks.stopChan <- struct{}{}
-	ks.isRunning = false
+	ks.cancel()
+	ks.cancel = nil
+	ks.mutex.Unlock()
 	ks.logger.Info("Stopping KubeScanner")
 	shutdownWg := make(chan struct{}, 1)
 	go func() {
@@ -77,6 +131,7 @@ func (ks *KubeScanner) Shutdown(ctx context.Context) error {
 	}()
 	select {
 	case <-shutdownWg:
+		ks.clients.stopAll()
 		ks.logger.Info("KubeScanner successfully stopped")
 	case <-ctx.Done():
 		ks.logger.Info("KubeScanner is forced to stop")
@@ -85,125 +140,284 @@ func (ks *KubeScanner) Shutdown(ctx context.Context) error {
 	return nil
 }
 
-// ScanAll scans all configs and namespaces from model.ClusterDAOI and saved them into model.ScansDAOI
-func (ks *KubeScanner) ScanAll() {
-	clusters, err := ks.storage.GetAllClusters()
+// ScanNamespace return scans for jobs, services and events into specific Namespace for cluster.
+// Only pods and containers which pass namespace filters are scanned
+//
+//	Scan is limited by scan timeout. If ctx is cancelled or timeout is exceeded, partial results are discarded
+//	and nothing is saved. Checkpoints of completely read containers are kept, so the next scan continues from them
+func (ks *KubeScanner) ScanNamespace(ctx context.Context, cluster model.Cluster, namespace model.Namespace) error {
+	_, err := ks.scanNamespace(ctx, cluster, namespace, "")
+	return err
+}
+
+// scanNamespace scans namespace and reports progress into on-demand scan with taskID, empty taskID means periodic scan.
+//
+//	Scans of the same namespace don't run simultaneously, so containers logs aren't read twice.
+//	Result of every scan attempt is saved as namespace scan status
+func (ks *KubeScanner) scanNamespace(ctx context.Context, cluster model.Cluster, namespace model.Namespace,
+	taskID string) (result *model.NamespaceScanResult, err error) {
+	// Stop scanning if app are shutting down
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("scan was cancelled: %w", ctx.Err())
+	}
+	namespaceLock := ks.namespaceLock(cluster.Name, namespace.Name)
+	namespaceLock.Lock()
+	defer namespaceLock.Unlock()
+	ks.tasks.update(taskID, func(task *model.ScanTask) {
+		if task.Status == model.ScanQueued {
+			task.Status = model.ScanRunning
+			task.StartedAt = time.Now()
+		}
+	})
+	status := model.ScanStatus{LastAttemptTime: time.Now()}
+	defer func() {
+		status.Duration = time.Since(status.LastAttemptTime)
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.LastSuccessTime = time.Now()
+		}
+		saveErr := ks.storage.SaveScanStatus(cluster.Name, namespace.Name, status)
+		if saveErr != nil {
+			ks.logger.
+				WithField("error", saveErr).
+				Errorf("Failed to save scan status of namespace %s in cluster %s", namespace.Name, cluster.Name)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, ks.scanTimeout)
+	defer cancel()
+	client, err := ks.clients.get(cluster)
 	if err != nil {
 		ks.logger.
 			WithField("error", err).
-			Error("failed to get clusters from DB")
-		return
-	}
-	for _, cluster := range clusters {
-		ks.ScanCluster(cluster)
-	}
-}
-
-func (ks *KubeScanner) ScanCluster(cluster model.Cluster) {
-	ks.logger.Tracef("Start scanning cluster %s", cluster.Name)
-	wg := sync.WaitGroup{}
-	for _, namespace := range cluster.Namespaces {
-		wg.Add(1)
-		go func(ns string) {
-			defer wg.Done()
-			ks.logger.Tracef("Start scanning namespace %s in cluster %s", ns, cluster.Name)
-			err := ks.ScanNamespace(cluster, ns)
-			if err != nil {
-				ks.logger.
-					WithField("error", err).
-					Errorf("Failed to scan namespace %s in cluster %s", ns, cluster.Name)
-			} else {
-				ks.logger.Tracef("Successfully scanned namespace %s in cluster %s", ns, cluster.Name)
-			}
-		}(namespace)
+			Errorf("Failed to initialize kubernetes client for cluster %s", cluster.Name)
+		return nil, err
 	}
-	wg.Wait()
-	ks.logger.Tracef("%s cluster scan completed", cluster.Name)
-}
-
-// ScanNamespace return scans for jobs and services into specific Namespace for cluster
-func (ks *KubeScanner) ScanNamespace(cluster model.Cluster, namespace string) error {
-	// Stop scanning if app are shutting down
-	if !ks.isRunning {
-		return fmt.Errorf("service was stopped, abort all scans")
+	kubeClient := client.kubeClient
+	// Checkpoints saved by the previous scanner run are needed before any container log is read
+	err = ks.checkpoints.load(cluster.Name, namespace.Name, ks.storage)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Errorf("Failed to load checkpoints of namespace %s in cluster %s", namespace.Name, cluster.Name)
+		return nil, err
 	}
-	// Init kubernetes REST
-	kubeRest, err := clientcmd.RESTConfigFromKubeConfig([]byte(cluster.Config))
+	filter, err := newPodFilter(namespace.Filters)
 	if err != nil {
 		ks.logger.
 			WithField("error", err).
-			Error("Failed to initialize kubernetes config from DB string")
-		return err
+			Errorf("Failed to parse pods filters of namespace %s in cluster %s", namespace.Name, cluster.Name)
+		return nil, err
 	}
-	kubeRest.Timeout = time.Duration(*ks.kubernetesTimeout) * time.Second
-	kubeClient, err := kubernetes.NewForConfig(kubeRest)
+	grepRules, err := newGrepRuleSet(effectiveGrepRules(ks.grepRules, cluster, namespace))
 	if err != nil {
 		ks.logger.
 			WithField("error", err).
-			Errorf("Failed to initialize kubernetes config client set for cluster %s", cluster.Name)
-		return err
+			Errorf("Failed to parse grep rules of namespace %s in cluster %s", namespace.Name, cluster.Name)
+		return nil, err
 	}
-	// List all pods
-	pods, err := kubeClient.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	// List all pods from informer cache
+	pods, err := client.listPods(ctx, namespace.Name)
 	if err != nil {
 		ks.logger.
 			WithField("error", err).
-			Errorf("Failed to list all pods using kubernetes ClientSet for cluster %s", cluster.Name)
-		return err
+			Errorf("Failed to list all pods of namespace %s for cluster %s", namespace.Name, cluster.Name)
+		return nil, err
+	}
+	scannedPods := make([]*v1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if filter.matches(pod) {
+			scannedPods = append(scannedPods, pod)
+		}
 	}
+	status.PodsCount = len(scannedPods)
+	ks.tasks.update(taskID, func(task *model.ScanTask) {
+		task.PodsTotal += len(scannedPods)
+	})
 	var (
 		servicesScans = make([]model.ServiceScan, 0)
 		jobsScans     = make([]model.JobScan, 0)
+		jobsLogs      = make([]*unsavedJobLog, 0)
 	)
-	// Scan gotten pods
-	wg := sync.WaitGroup{}
+	logParser := namespace.LogParser
+	if logParser == "" {
+		logParser = cluster.LogParser
+	}
+	workloads := ks.newWorkloadResolver(ctx, client, namespace.Name)
+	// Events are optional part of scan, namespace is scanned without them if they are disabled or can't be listed
+	eventsScans := make([]model.EventScan, 0)
+	if !namespace.Resources.DisableEvents {
+		eventsScans, err = ks.scanEvents(ctx, client, namespace.Name)
+		if err != nil {
+			ks.logger.
+				WithField("error", err).
+				Warningf("Failed to list events of namespace %s in cluster %s", namespace.Name, cluster.Name)
+			eventsScans = make([]model.EventScan, 0)
+		}
+	}
+	// CronJobs are optional as well
+	cronJobsScans := make([]model.CronJobScan, 0)
+	if !namespace.Resources.DisableCronJobs {
+		cronJobsScans, err = ks.scanCronJobs(ctx, client, namespace.Name, workloads)
+		if err != nil {
+			ks.logger.
+				WithField("error", err).
+				Warningf("Failed to list cron jobs of namespace %s in cluster %s", namespace.Name, cluster.Name)
+			cronJobsScans = make([]model.CronJobScan, 0)
+		}
+	}
+	// Scan gotten pods by workers pool. Pods which failed to be scanned are reported in scan status
 	mutex := sync.Mutex{}
-	for _, pod := range pods.Items {
-		wg.Add(1)
-		time.Sleep(time.Second / 5) //  Else stack on cluster limits and get Error from time to time
-		go func(p v1.Pod) {
-			defer wg.Done()
+	failedPods := make([]string, 0)
+	failPod := func(pod string, err error) {
+		mutex.Lock()
+		failedPods = append(failedPods, fmt.Sprintf("%s: %s", pod, err))
+		mutex.Unlock()
+	}
+	tasks := make([]func(), 0, len(scannedPods))
+	for _, pod := range scannedPods {
+		p := *pod
+		tasks = append(tasks, func() {
+			defer ks.tasks.update(taskID, func(task *model.ScanTask) {
+				task.PodsDone++
+			})
+			if ctx.Err() != nil {
+				return
+			}
 			switch p.Status.Phase {
-			case v1.PodRunning:
-				serviceScan, err := ks.scanServiceLog(kubeClient, &p)
+			case v1.PodRunning, v1.PodPending, v1.PodUnknown:
+				serviceScan, err := ks.scanServiceLog(ctx, kubeClient, cluster.Name, &p, logParser, filter)
 				if err != nil {
 					ks.logger.
 						WithField("error", err).
 						Errorf("Error occured while scanning pod %s logs", p.Name)
+					failPod(p.Name, err)
 					return
 				}
+				serviceScan.WorkloadKind, serviceScan.WorkloadName = workloads.resolve(&p)
+				serviceScan.Events = podEvents(eventsScans, &p)
+				serviceScan.Diagnostics = podDiagnostics(&p, serviceScan.Events)
 				mutex.Lock()
 				servicesScans = append(servicesScans, *serviceScan)
 				mutex.Unlock()
 			case v1.PodFailed, v1.PodSucceeded:
-				jobScan, err := ks.scanJobLog(kubeClient, &p)
+				container := filter.jobContainer(&p)
+				if container == "" {
+					return
+				}
+				jobScan, jobLog, err := ks.scanJobLog(ctx, kubeClient, cluster.Name, &p, container, grepRules)
 				if err != nil {
 					ks.logger.
 						WithField("error", err).
 						Errorf("Error occured while getting pod %s logs", p.Name)
+					failPod(p.Name, err)
 					return
 				}
+				jobScan.WorkloadKind, jobScan.WorkloadName = workloads.resolve(&p)
+				jobScan.Events = podEvents(eventsScans, &p)
+				jobScan.Diagnostics = podDiagnostics(&p, jobScan.Events)
+				jobScan.Execution = jobExecution(&p, container, workloads.job(&p))
 				mutex.Lock()
 				jobsScans = append(jobsScans, *jobScan)
+				if jobLog != nil {
+					jobsLogs = append(jobsLogs, jobLog)
+				}
 				mutex.Unlock()
 			}
-		}(pod)
+		})
+	}
+	ks.pool.run(tasks)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("scan was interrupted, partial results are discarded: %w", ctx.Err())
 	}
-	wg.Wait()
+	status.Error = failedPodsError(failedPods, len(scannedPods))
+	// Forget checkpoints of pods which were removed from namespace. Checkpoints of filtered out pods are kept,
+	// so they aren't read from scratch when filters are changed back
+	alivePods := make(map[types.UID]struct{}, len(pods))
+	for _, pod := range pods {
+		alivePods[pod.UID] = struct{}{}
+	}
+	ks.checkpoints.prune(cluster.Name, namespace.Name, alivePods)
 	// Save all scans result
-	err = ks.storage.UpdateServicesScans(cluster.Name, namespace, servicesScans)
+	err = ks.storage.UpdateServicesScans(cluster.Name, namespace.Name, servicesScans)
 	if err != nil {
 		ks.logger.
 			WithField("error", err).
 			Error("failed to save services scans")
-		return err
+		return nil, err
 	}
-	err = ks.storage.UpdateJobsScans(cluster.Name, namespace, jobsScans)
+	err = ks.storage.UpdateJobsScans(cluster.Name, namespace.Name, jobsScans)
 	if err != nil {
 		ks.logger.
 			WithField("error", err).
 			Error("failed to save jobs scans")
-		return err
+		return nil, err
 	}
-	return nil
+	// Jobs logs are saved only when scan isn't interrupted and scans which refer to them are saved
+	for _, jobLog := range jobsLogs {
+		ks.saveJobLog(jobLog)
+	}
+	// Logs of jobs which pods were removed from namespace aren't needed anymore. Logs of pods which weren't scanned
+	// this time (e.g. filtered out or failed to be read) are kept
+	podsNames := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		podsNames = append(podsNames, pod.Name)
+	}
+	err = ks.storage.PruneJobLogs(cluster.Name, namespace.Name, podsNames)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Warning("failed to delete logs of removed jobs")
+	}
+	err = ks.storage.UpdateEventsScans(cluster.Name, namespace.Name, eventsScans)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Error("failed to save events scans")
+		return nil, err
+	}
+	err = ks.storage.UpdateCronJobsScans(cluster.Name, namespace.Name, cronJobsScans)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Error("failed to save cron jobs scans")
+		return nil, err
+	}
+	// Checkpoints which can't be saved stay changed in memory and are saved by the next scan
+	err = ks.checkpoints.save(cluster.Name, namespace.Name, alivePods, ks.storage)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Warning("failed to save checkpoints")
+	}
+	return &model.NamespaceScanResult{
+		Namespace:     namespace.Name,
+		ServicesScans: len(servicesScans),
+		JobsScans:     len(jobsScans),
+		EventsScans:   len(eventsScans),
+		CronJobsScans: len(cronJobsScans),
+	}, nil
+}
+
+// failedPodsError describes pods which failed to be scanned by otherwise successful scan, only the first
+// maxFailedPodsErrors errors are listed. Empty string is returned if all pods are scanned
+func failedPodsError(failedPods []string, podsCount int) string {
+	if len(failedPods) == 0 {
+		return ""
+	}
+	sort.Strings(failedPods)
+	listed := failedPods
+	if len(listed) > maxFailedPodsErrors {
+		listed = listed[:maxFailedPodsErrors]
+	}
+	message := fmt.Sprintf("%d of %d pods failed to be scanned: %s", len(failedPods), podsCount, strings.Join(listed, "; "))
+	if len(listed) < len(failedPods) {
+		message += fmt.Sprintf("; and %d more", len(failedPods)-len(listed))
+	}
+	return message
+}
+
+func (ks *KubeScanner) namespaceLock(clusterName string, namespaceName string) *sync.Mutex {
+	lock, _ := ks.namespaceLocks.LoadOrStore(clusterName+"/"+namespaceName, &sync.Mutex{})
+	return lock.(*sync.Mutex)
 }
@@ -0,0 +1,37 @@
+package kube
+
+import "testing"
+
+func TestFailedPodsError(t *testing.T) {
+	tests := []struct {
+		name       string
+		failedPods []string
+		podsCount  int
+		want       string
+	}{
+		{
+			name:      "all pods are scanned",
+			podsCount: 3,
+			want:      "",
+		},
+		{
+			name:       "pods are sorted",
+			failedPods: []string{"b: timeout", "a: forbidden"},
+			podsCount:  3,
+			want:       "2 of 3 pods failed to be scanned: a: forbidden; b: timeout",
+		},
+		{
+			name:       "only the first errors are listed",
+			failedPods: []string{"a: 1", "b: 2", "c: 3", "d: 4", "e: 5", "f: 6", "g: 7"},
+			podsCount:  10,
+			want:       "7 of 10 pods failed to be scanned: a: 1; b: 2; c: 3; d: 4; e: 5; and 2 more",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failedPodsError(tt.failedPods, tt.podsCount); got != tt.want {
+				t.Errorf("failedPodsError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
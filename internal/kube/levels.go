@@ -0,0 +1,58 @@
+package kube
+
+import (
+	"github.com/sirupsen/logrus"
+	"scan_project/internal/model"
+	"strings"
+)
+
+// defaultLevelsAliases maps spellings of log levels of popular loggers, bunyan/pino numeric levels
+// and syslog severities onto model levels
+var defaultLevelsAliases = map[string][]string{
+	model.Trace:   {"trace", "trc", "verbose", "vrb", "10"},
+	model.Debug:   {"debug", "dbg", "dbug", "20", "7"},
+	model.Info:    {"info", "inf", "information", "informational", "notice", "30", "6", "5"},
+	model.Warning: {"warning", "warn", "wrn", "40", "4"},
+	model.Error:   {"error", "err", "eror", "50", "3"},
+	model.Fatal:   {"fatal", "ftl", "panic", "dpanic", "critical", "crit", "alert", "emerg", "emergency", "60", "0", "1", "2"},
+}
+
+// levelNormalizer maps log levels found by LogParser onto model levels
+type levelNormalizer struct {
+	aliases map[string]string
+}
+
+// newLevelNormalizer builds normalizer from defaultLevelsAliases extended by customAliases.
+//
+//	customAliases keys must be model levels, aliases of other keys are skipped.
+//	Custom alias overrides default one, aliases are case-insensitive
+func newLevelNormalizer(customAliases map[string][]string, logger *logrus.Entry) *levelNormalizer {
+	ln := &levelNormalizer{
+		aliases: make(map[string]string),
+	}
+	for level, aliases := range defaultLevelsAliases {
+		for _, alias := range aliases {
+			ln.aliases[alias] = level
+		}
+	}
+	for level, aliases := range customAliases {
+		if _, ok := defaultLevelsAliases[level]; !ok {
+			logger.Warningf("Aliases of unknown log level %s are skipped", level)
+			continue
+		}
+		for _, alias := range aliases {
+			ln.aliases[strings.ToLower(strings.TrimSpace(alias))] = level
+		}
+	}
+	return ln
+}
+
+// normalize returns model level for level alias. If alias is unknown, trimmed level is returned with ok=false
+func (ln *levelNormalizer) normalize(level string) (normalized string, ok bool) {
+	level = strings.TrimSpace(level)
+	normalized, ok = ln.aliases[strings.ToLower(level)]
+	if !ok {
+		return level, false
+	}
+	return normalized, true
+}
@@ -0,0 +1,40 @@
+package kube
+
+import (
+	"github.com/sirupsen/logrus"
+	"scan_project/internal/model"
+	"testing"
+)
+
+func TestLevelNormalizer(t *testing.T) {
+	normalizer := newLevelNormalizer(map[string][]string{
+		model.Warning: {" Attention "},
+		model.Error:   {"severe", "warn"},
+		"unknown":     {"custom"},
+	}, logrus.NewEntry(logrus.New()))
+	tests := []struct {
+		level  string
+		want   string
+		wantOk bool
+	}{
+		{level: "INFO", want: model.Info, wantOk: true},
+		{level: " Warning ", want: model.Warning, wantOk: true},
+		{level: "50", want: model.Error, wantOk: true},
+		{level: "dpanic", want: model.Fatal, wantOk: true},
+		{level: "attention", want: model.Warning, wantOk: true},
+		{level: "SEVERE", want: model.Error, wantOk: true},
+		// Custom alias overrides default one
+		{level: "warn", want: model.Error, wantOk: true},
+		// Aliases of unknown levels are skipped
+		{level: "custom", want: "custom", wantOk: false},
+		{level: " Notable ", want: "Notable", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, ok := normalizer.normalize(tt.level)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("normalize(%q) = %q, %v, want %q, %v", tt.level, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package kube
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// maxEventLines and maxEventBytes limit a single log event, so a start pattern which never matches or a huge
+	// stack trace doesn't keep the whole log in memory. Event is emitted when it reaches any of the limits and the
+	// next lines start a new event
+	maxEventLines = 1000
+	maxEventBytes = 256 << 10
+)
+
+// multilineRule describes lines which belong to the previous log event
+type multilineRule struct {
+	// start begins multiline block (e.g. python traceback header), nil means that continuation is checked for any line
+	start *regexp.Regexp
+	// attachStart makes the start line a part of the previous event instead of a new event
+	attachStart bool
+	// continuation matches lines continuing the event or the block
+	continuation *regexp.Regexp
+	// terminated means that the first line of the block which doesn't match continuation ends the block and belongs to it
+	terminated bool
+}
+
+// multilinePresets are built-in rules for stack traces of popular languages
+var multilinePresets = map[string][]*multilineRule{
+	"java": {
+		{
+			continuation: regexp.MustCompile(
+				`^\s+at |^\s+\.\.\. \d+ (more|common frames omitted)|^Caused by: |^\s*Suppressed: |^\s*[\w$]+(\.[\w$]+)+(Exception|Error|Throwable)\b`),
+		},
+	},
+	"python": {
+		{
+			start:        regexp.MustCompile(`^Traceback \(most recent call last\):`),
+			attachStart:  true,
+			continuation: regexp.MustCompile(`^\s`),
+			terminated:   true,
+		},
+		{
+			continuation: regexp.MustCompile(`^During handling of the above exception|^The above exception was the direct cause`),
+		},
+	},
+	"go": {
+		{
+			start:        regexp.MustCompile(`^(panic|fatal error): `),
+			continuation: regexp.MustCompile(`^$|^goroutine \d+ \[|^\t|^[\w./*()\[\]\-]+\(.*\)$|^created by |^\[(signal|recovered)|^exit status`),
+		},
+	},
+}
+
+// multilineConfig is the set of rules used to assemble log events from physical lines
+type multilineConfig struct {
+	rules []*multilineRule
+	// startRegexp matches the first line of event, other lines continue the previous event. nil means no such pattern
+	startRegexp *regexp.Regexp
+}
+
+// newMultilineConfig builds multiline rules of presets and custom start of event pattern.
+//
+//	nil presets means that all built-in presets are used
+func newMultilineConfig(presets []string, startPattern string) (multilineConfig, error) {
+	var cfg multilineConfig
+	if presets == nil {
+		presets = []string{"java", "python", "go"}
+	}
+	for _, preset := range presets {
+		rules, ok := multilinePresets[strings.ToLower(preset)]
+		if !ok {
+			return cfg, fmt.Errorf("unknown multiline preset %s", preset)
+		}
+		cfg.rules = append(cfg.rules, rules...)
+	}
+	if startPattern != "" {
+		startRegexp, err := regexp.Compile(startPattern)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.startRegexp = startRegexp
+	}
+	return cfg, nil
+}
+
+// logEvent is log entry, which may consist of several physical lines (e.g. stack trace)
+type logEvent struct {
+	time  time.Time // time of the first line
+	lines []string
+	size  int // total size of lines
+}
+
+func (e *logEvent) text() string {
+	return strings.Join(e.lines, "\n")
+}
+
+// multilineAssembler groups physical log lines into events and passes every completed event to emit
+type multilineAssembler struct {
+	cfg     multilineConfig
+	current *logEvent
+	block   *multilineRule // rule of the multiline block which is being assembled
+	emit    func(event *logEvent)
+}
+
+func newMultilineAssembler(cfg multilineConfig, emit func(event *logEvent)) *multilineAssembler {
+	return &multilineAssembler{
+		cfg:  cfg,
+		emit: emit,
+	}
+}
+
+// add appends line to the current event or emits the current event and starts a new one
+func (ma *multilineAssembler) add(logTime time.Time, line []byte) {
+	if ma.continues(line) {
+		ma.current.lines = append(ma.current.lines, string(line))
+		ma.current.size += len(line)
+		if len(ma.current.lines) >= maxEventLines || ma.current.size >= maxEventBytes {
+			ma.flush()
+		}
+		return
+	}
+	ma.flush()
+	ma.current = &logEvent{
+		time:  logTime,
+		lines: []string{string(line)},
+		size:  len(line),
+	}
+	for _, rule := range ma.cfg.rules {
+		if rule.start != nil && rule.start.Match(line) {
+			ma.block = rule
+			break
+		}
+	}
+}
+
+// flush emits the current event. Should be called when log is over
+func (ma *multilineAssembler) flush() {
+	if ma.current != nil {
+		ma.emit(ma.current)
+	}
+	ma.current = nil
+	ma.block = nil
+}
+
+// continues checks if line belongs to the current event. Without open event any line starts a new one,
+// e.g. exception line of java stack trace which follows the flushed event
+func (ma *multilineAssembler) continues(line []byte) bool {
+	if ma.current == nil {
+		return false
+	}
+	if ma.block != nil {
+		if ma.block.continuation.Match(line) {
+			return true
+		}
+		block := ma.block
+		ma.block = nil
+		if block.terminated {
+			return true
+		}
+	}
+	for _, rule := range ma.cfg.rules {
+		if rule.start != nil {
+			if rule.attachStart && rule.start.Match(line) {
+				ma.block = rule
+				return true
+			}
+			continue
+		}
+		if rule.continuation.Match(line) {
+			return true
+		}
+	}
+	return ma.cfg.startRegexp != nil && !ma.cfg.startRegexp.Match(line)
+}
@@ -0,0 +1,190 @@
+package kube
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultilineAssembler(t *testing.T) {
+	tests := []struct {
+		name         string
+		presets      []string
+		startPattern string
+		lines        []string
+		want         [][]string
+	}{
+		{
+			name: "java stack trace",
+			lines: []string{
+				"ERROR request failed",
+				"java.lang.IllegalStateException: boom",
+				"\tat com.example.Service.run(Service.java:10)",
+				"Caused by: java.io.IOException: closed",
+				"\t... 3 more",
+				"INFO next request",
+			},
+			want: [][]string{
+				{
+					"ERROR request failed",
+					"java.lang.IllegalStateException: boom",
+					"\tat com.example.Service.run(Service.java:10)",
+					"Caused by: java.io.IOException: closed",
+					"\t... 3 more",
+				},
+				{"INFO next request"},
+			},
+		},
+		{
+			name: "java exception line without open event starts event",
+			lines: []string{
+				"java.lang.IllegalStateException: boom",
+				"\tat com.example.Service.run(Service.java:10)",
+			},
+			want: [][]string{
+				{"java.lang.IllegalStateException: boom", "\tat com.example.Service.run(Service.java:10)"},
+			},
+		},
+		{
+			name: "python traceback",
+			lines: []string{
+				"ERROR handler failed",
+				"Traceback (most recent call last):",
+				`  File "app.py", line 1, in <module>`,
+				"    handle()",
+				"ValueError: bad value",
+				"INFO next request",
+			},
+			want: [][]string{
+				{
+					"ERROR handler failed",
+					"Traceback (most recent call last):",
+					`  File "app.py", line 1, in <module>`,
+					"    handle()",
+					"ValueError: bad value",
+				},
+				{"INFO next request"},
+			},
+		},
+		{
+			name: "go panic",
+			lines: []string{
+				"panic: runtime error: invalid memory address",
+				"",
+				"goroutine 1 [running]:",
+				"main.main()",
+				"\t/app/main.go:10 +0x1d",
+				"exit status 2",
+				"INFO restarted",
+			},
+			want: [][]string{
+				{
+					"panic: runtime error: invalid memory address",
+					"",
+					"goroutine 1 [running]:",
+					"main.main()",
+					"\t/app/main.go:10 +0x1d",
+					"exit status 2",
+				},
+				{"INFO restarted"},
+			},
+		},
+		{
+			name:    "presets are disabled",
+			presets: []string{},
+			lines: []string{
+				"ERROR request failed",
+				"\tat com.example.Service.run(Service.java:10)",
+			},
+			want: [][]string{
+				{"ERROR request failed"},
+				{"\tat com.example.Service.run(Service.java:10)"},
+			},
+		},
+		{
+			name:         "custom start pattern",
+			presets:      []string{},
+			startPattern: `^\d{4}-\d{2}-\d{2} `,
+			lines: []string{
+				"2023-11-09 22:25:47 first",
+				"continuation",
+				"2023-11-09 22:25:48 second",
+			},
+			want: [][]string{
+				{"2023-11-09 22:25:47 first", "continuation"},
+				{"2023-11-09 22:25:48 second"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newMultilineConfig(tt.presets, tt.startPattern)
+			if err != nil {
+				t.Fatalf("newMultilineConfig() error = %v", err)
+			}
+			got := assembleLines(cfg, tt.lines)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assembled events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultilineAssemblerLimits(t *testing.T) {
+	cfg, err := newMultilineConfig([]string{}, `^START`)
+	if err != nil {
+		t.Fatalf("newMultilineConfig() error = %v", err)
+	}
+	longLine := strings.Repeat("x", 100<<10)
+	tests := []struct {
+		name  string
+		lines []string
+		want  []int
+	}{
+		{
+			name:  "lines limit",
+			lines: append([]string{"START"}, repeatLine("x", maxEventLines+5)...),
+			want:  []int{maxEventLines, 6},
+		},
+		{
+			name:  "bytes limit",
+			lines: append([]string{"START"}, repeatLine(longLine, 4)...),
+			want:  []int{4, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := assembleLines(cfg, tt.lines)
+			got := make([]int, 0, len(events))
+			for _, event := range events {
+				got = append(got, len(event))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events lines = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewMultilineConfigErrors(t *testing.T) {
+	if _, err := newMultilineConfig([]string{"cobol"}, ""); err == nil {
+		t.Error("unknown preset is accepted")
+	}
+	if _, err := newMultilineConfig(nil, "("); err == nil {
+		t.Error("invalid start pattern is accepted")
+	}
+}
+
+// assembleLines passes lines through multilineAssembler and returns lines of assembled events
+func assembleLines(cfg multilineConfig, lines []string) [][]string {
+	events := make([][]string, 0)
+	assembler := newMultilineAssembler(cfg, func(event *logEvent) {
+		events = append(events, event.lines)
+	})
+	for _, line := range lines {
+		assembler.add(time.Time{}, []byte(line))
+	}
+	assembler.flush()
+	return events
+}
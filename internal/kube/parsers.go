@@ -0,0 +1,359 @@
+package kube
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"scan_project/internal/model"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AutoLogParser is the name of log parser setting which makes scanner detect log format of every container
+const AutoLogParser = "auto"
+
+// parserDetectionVotes is the number of lines matched by the same parser after which container log format is chosen
+const parserDetectionVotes = 10
+
+// LogEntry is log line parsed by LogParser
+type LogEntry struct {
+	Level   string
+	Message string
+}
+
+// LogParser recognises log lines of the specific format
+type LogParser interface {
+	// Name is used to choose parser in cluster and namespace settings
+	Name() string
+	// Parse returns entry of the log line. ok is false when line has another format or has no log level
+	Parse(line []byte) (entry LogEntry, ok bool)
+}
+
+// logParsers are built-in parsers in the order of attempts while detecting container log format
+var logParsers = []LogParser{
+	&jsonLogParser{},
+	&logfmtLogParser{},
+	&klogLogParser{},
+	&springLogParser{},
+	&nginxLogParser{},
+}
+
+// LogParsersNames returns names of all built-in log parsers and AutoLogParser
+func LogParsersNames() []string {
+	names := make([]string, 0, len(logParsers)+1)
+	names = append(names, AutoLogParser)
+	for _, parser := range logParsers {
+		names = append(names, parser.Name())
+	}
+	return names
+}
+
+func getLogParser(name string) LogParser {
+	for _, parser := range logParsers {
+		if parser.Name() == name {
+			return parser
+		}
+	}
+	return nil
+}
+
+// containerLogParser parses container log lines with the chosen parser or detects log format when parser isn't chosen
+type containerLogParser struct {
+	parser LogParser
+	votes  map[string]int
+}
+
+// newContainerLogParser returns parser for container log. Empty name or AutoLogParser start log format detection
+func newContainerLogParser(name string) *containerLogParser {
+	return &containerLogParser{
+		parser: getLogParser(name),
+		votes:  make(map[string]int),
+	}
+}
+
+// name returns name of the chosen parser or empty string when log format is not detected yet
+func (clp *containerLogParser) name() string {
+	if clp.parser == nil {
+		return ""
+	}
+	return clp.parser.Name()
+}
+
+// parse parses log line. While log format is not detected, every built-in parser is tried
+func (clp *containerLogParser) parse(line []byte) (LogEntry, bool) {
+	if clp.parser != nil {
+		return clp.parser.Parse(line)
+	}
+	for _, parser := range logParsers {
+		entry, ok := parser.Parse(line)
+		if !ok {
+			continue
+		}
+		clp.votes[parser.Name()]++
+		if clp.votes[parser.Name()] >= parserDetectionVotes {
+			clp.parser = parser
+		}
+		return entry, true
+	}
+	return LogEntry{}, false
+}
+
+// finishDetection chooses the parser which recognised most of lines, if log format is not detected yet
+func (clp *containerLogParser) finishDetection() {
+	if clp.parser != nil || len(clp.votes) == 0 {
+		return
+	}
+	names := make([]string, 0, len(clp.votes))
+	for name := range clp.votes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if clp.votes[names[i]] != clp.votes[names[j]] {
+			return clp.votes[names[i]] > clp.votes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	clp.parser = getLogParser(names[0])
+}
+
+// jsonLogParser parses json logs of logrus, zap, bunyan, serilog and similar loggers
+type jsonLogParser struct{}
+
+var (
+	jsonLevelKeys   = []string{"level", "severity", "lvl", "loglevel", "log.level", "@l"}
+	jsonMessageKeys = []string{"msg", "message", "@m", "@mt"}
+)
+
+func (p *jsonLogParser) Name() string {
+	return "json"
+}
+
+func (p *jsonLogParser) Parse(line []byte) (LogEntry, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '{' {
+		return LogEntry{}, false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return LogEntry{}, false
+	}
+	var entry LogEntry
+	for _, key := range jsonLevelKeys {
+		if value, ok := fields[key]; ok {
+			entry.Level = jsonValueToString(value)
+			break
+		}
+	}
+	if entry.Level == "" {
+		return LogEntry{}, false
+	}
+	for _, key := range jsonMessageKeys {
+		if value, ok := fields[key]; ok {
+			entry.Message = jsonValueToString(value)
+			break
+		}
+	}
+	return entry, true
+}
+
+// jsonValueToString returns json string value or raw json of other types (e.g. numeric log levels)
+func jsonValueToString(value json.RawMessage) string {
+	var str string
+	if err := json.Unmarshal(value, &str); err == nil {
+		return str
+	}
+	return string(bytes.TrimSpace(value))
+}
+
+// logfmtLogParser parses logfmt logs, e.g. `level=warn msg="connection lost"`
+type logfmtLogParser struct{}
+
+var (
+	logfmtLevelKeys   = []string{"level", "lvl", "severity"}
+	logfmtMessageKeys = []string{"msg", "message"}
+)
+
+func (p *logfmtLogParser) Name() string {
+	return "logfmt"
+}
+
+func (p *logfmtLogParser) Parse(line []byte) (LogEntry, bool) {
+	if bytes.IndexByte(line, '=') < 0 {
+		return LogEntry{}, false
+	}
+	fields := parseLogfmt(line)
+	var entry LogEntry
+	for _, key := range logfmtLevelKeys {
+		if value, ok := fields[key]; ok {
+			entry.Level = value
+			break
+		}
+	}
+	if entry.Level == "" {
+		return LogEntry{}, false
+	}
+	for _, key := range logfmtMessageKeys {
+		if value, ok := fields[key]; ok {
+			entry.Message = value
+			break
+		}
+	}
+	return entry, true
+}
+
+// parseLogfmt splits logfmt line into key-value pairs. Keys without values are skipped
+func parseLogfmt(line []byte) map[string]string {
+	fields := make(map[string]string)
+	i := 0
+	for i < len(line) {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		keyStart := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := string(line[keyStart:i])
+		if i >= len(line) || line[i] != '=' {
+			continue
+		}
+		i++
+		var value string
+		if i < len(line) && line[i] == '"' {
+			i++
+			var sb strings.Builder
+			for i < len(line) && line[i] != '"' {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				sb.WriteByte(line[i])
+				i++
+			}
+			i++
+			value = sb.String()
+		} else {
+			valueStart := i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = string(line[valueStart:i])
+		}
+		if key != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// klogLogParser parses logs of kubernetes components, e.g. `E1018 12:00:00.000000   1 main.go:10] message`
+type klogLogParser struct{}
+
+var (
+	klogRegexp = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ [^\]]+\] ?(.*)$`)
+	klogLevels = map[string]string{
+		"I": model.Info,
+		"W": model.Warning,
+		"E": model.Error,
+		"F": model.Fatal,
+	}
+)
+
+func (p *klogLogParser) Name() string {
+	return "klog"
+}
+
+func (p *klogLogParser) Parse(line []byte) (LogEntry, bool) {
+	match := klogRegexp.FindSubmatch(line)
+	if match == nil {
+		return LogEntry{}, false
+	}
+	return LogEntry{
+		Level:   klogLevels[string(match[1])],
+		Message: string(match[2]),
+	}, true
+}
+
+// springLogParser parses default Spring Boot text logs,
+// e.g. `2023-11-09 22:25:47.531  WARN 1 --- [main] c.e.Application : message`
+type springLogParser struct{}
+
+var (
+	springRegexp = regexp.MustCompile(
+		`^\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}[.,]\d+\S*\s+(TRACE|DEBUG|INFO|WARN|ERROR|FATAL)\s+\d*\s*---\s+\[[^\]]*\]\s+\S+\s*:\s?(.*)$`)
+	springLevels = map[string]string{
+		"TRACE": model.Trace,
+		"DEBUG": model.Debug,
+		"INFO":  model.Info,
+		"WARN":  model.Warning,
+		"ERROR": model.Error,
+		"FATAL": model.Fatal,
+	}
+)
+
+func (p *springLogParser) Name() string {
+	return "spring"
+}
+
+func (p *springLogParser) Parse(line []byte) (LogEntry, bool) {
+	match := springRegexp.FindSubmatch(line)
+	if match == nil {
+		return LogEntry{}, false
+	}
+	return LogEntry{
+		Level:   springLevels[string(match[1])],
+		Message: string(match[2]),
+	}, true
+}
+
+// nginxLogParser parses nginx access logs in combined format and nginx error logs.
+//
+//	Access log level is taken from response status: 5xx is error, 4xx is warning, other statuses are info
+type nginxLogParser struct{}
+
+var (
+	nginxAccessRegexp = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]+\] "([^"]*)" (\d{3}) `)
+	nginxErrorRegexp  = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[(\w+)\] \d+#\d+: (.*)$`)
+	nginxErrorLevels  = map[string]string{
+		"debug":  model.Debug,
+		"info":   model.Info,
+		"notice": model.Info,
+		"warn":   model.Warning,
+		"error":  model.Error,
+		"crit":   model.Fatal,
+		"alert":  model.Fatal,
+		"emerg":  model.Fatal,
+	}
+)
+
+func (p *nginxLogParser) Name() string {
+	return "nginx"
+}
+
+func (p *nginxLogParser) Parse(line []byte) (LogEntry, bool) {
+	if match := nginxAccessRegexp.FindSubmatch(line); match != nil {
+		status, _ := strconv.Atoi(string(match[2]))
+		entry := LogEntry{
+			Level:   model.Info,
+			Message: string(match[1]) + " " + string(match[2]),
+		}
+		switch {
+		case status >= 500:
+			entry.Level = model.Error
+		case status >= 400:
+			entry.Level = model.Warning
+		}
+		return entry, true
+	}
+	if match := nginxErrorRegexp.FindSubmatch(line); match != nil {
+		level, ok := nginxErrorLevels[string(match[1])]
+		if !ok {
+			level = string(match[1])
+		}
+		return LogEntry{
+			Level:   level,
+			Message: string(match[2]),
+		}, true
+	}
+	return LogEntry{}, false
+}
@@ -0,0 +1,248 @@
+package kube
+
+import (
+	"reflect"
+	"scan_project/internal/model"
+	"testing"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want map[string]string
+	}{
+		{
+			name: "plain values",
+			line: `level=info msg=started port=8080`,
+			want: map[string]string{"level": "info", "msg": "started", "port": "8080"},
+		},
+		{
+			name: "quoted value with spaces",
+			line: `level=warn msg="connection lost" retry=3`,
+			want: map[string]string{"level": "warn", "msg": "connection lost", "retry": "3"},
+		},
+		{
+			name: "escaped quote",
+			line: `msg="say \"hi\"" level=debug`,
+			want: map[string]string{"msg": `say "hi"`, "level": "debug"},
+		},
+		{
+			name: "keys without values are skipped",
+			line: `flag level=error  other`,
+			want: map[string]string{"level": "error"},
+		},
+		{
+			name: "empty value",
+			line: `level= msg=x`,
+			want: map[string]string{"level": "", "msg": "x"},
+		},
+		{
+			name: "unterminated quote",
+			line: `msg="broken`,
+			want: map[string]string{"msg": "broken"},
+		},
+		{
+			name: "empty line",
+			line: ``,
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseLogfmt([]byte(tt.line))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLogfmt(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogParsers(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser LogParser
+		line   string
+		want   LogEntry
+		wantOk bool
+	}{
+		{
+			name:   "json logrus",
+			parser: &jsonLogParser{},
+			line:   `{"level":"error","msg":"db is down","time":"2023-11-09T22:25:47Z"}`,
+			want:   LogEntry{Level: "error", Message: "db is down"},
+			wantOk: true,
+		},
+		{
+			name:   "json serilog",
+			parser: &jsonLogParser{},
+			line:   `  {"@l":"Warning","@mt":"slow request {Path}"}`,
+			want:   LogEntry{Level: "Warning", Message: "slow request {Path}"},
+			wantOk: true,
+		},
+		{
+			name:   "json numeric level",
+			parser: &jsonLogParser{},
+			line:   `{"level":50,"msg":"bunyan error"}`,
+			want:   LogEntry{Level: "50", Message: "bunyan error"},
+			wantOk: true,
+		},
+		{
+			name:   "json without level",
+			parser: &jsonLogParser{},
+			line:   `{"msg":"no level"}`,
+		},
+		{
+			name:   "json invalid",
+			parser: &jsonLogParser{},
+			line:   `{"level":"info"`,
+		},
+		{
+			name:   "logfmt",
+			parser: &logfmtLogParser{},
+			line:   `ts=2023-11-09T22:25:47Z lvl=warn message="disk is almost full"`,
+			want:   LogEntry{Level: "warn", Message: "disk is almost full"},
+			wantOk: true,
+		},
+		{
+			name:   "logfmt without level",
+			parser: &logfmtLogParser{},
+			line:   `ts=2023-11-09T22:25:47Z msg=started`,
+		},
+		{
+			name:   "logfmt plain text",
+			parser: &logfmtLogParser{},
+			line:   `plain text line`,
+		},
+		{
+			name:   "klog error",
+			parser: &klogLogParser{},
+			line:   `E1018 12:00:00.000000       1 main.go:10] failed to sync`,
+			want:   LogEntry{Level: model.Error, Message: "failed to sync"},
+			wantOk: true,
+		},
+		{
+			name:   "klog info",
+			parser: &klogLogParser{},
+			line:   `I1018 12:00:00.123456 7 controller.go:42] started`,
+			want:   LogEntry{Level: model.Info, Message: "started"},
+			wantOk: true,
+		},
+		{
+			name:   "klog unknown severity",
+			parser: &klogLogParser{},
+			line:   `X1018 12:00:00.000000 1 main.go:10] message`,
+		},
+		{
+			name:   "spring warning",
+			parser: &springLogParser{},
+			line:   `2023-11-09 22:25:47.531  WARN 1 --- [main] c.e.Application : pool is exhausted`,
+			want:   LogEntry{Level: model.Warning, Message: "pool is exhausted"},
+			wantOk: true,
+		},
+		{
+			name:   "spring iso time without pid",
+			parser: &springLogParser{},
+			line:   `2023-11-09T22:25:47,531+03:00 ERROR --- [http-nio-8080-exec-1] o.a.c.c.C.[.[.[/] : Servlet failed`,
+			want:   LogEntry{Level: model.Error, Message: "Servlet failed"},
+			wantOk: true,
+		},
+		{
+			name:   "spring other format",
+			parser: &springLogParser{},
+			line:   `2023-11-09 22:25:47 WARN pool is exhausted`,
+		},
+		{
+			name:   "nginx access 5xx",
+			parser: &nginxLogParser{},
+			line:   `10.0.0.1 - - [09/Nov/2023:22:25:47 +0000] "GET /api HTTP/1.1" 502 157 "-" "curl/8.0"`,
+			want:   LogEntry{Level: model.Error, Message: "GET /api HTTP/1.1 502"},
+			wantOk: true,
+		},
+		{
+			name:   "nginx access 4xx",
+			parser: &nginxLogParser{},
+			line:   `10.0.0.1 - user [09/Nov/2023:22:25:47 +0000] "POST /login HTTP/1.1" 401 0 "-" "-"`,
+			want:   LogEntry{Level: model.Warning, Message: "POST /login HTTP/1.1 401"},
+			wantOk: true,
+		},
+		{
+			name:   "nginx access 2xx",
+			parser: &nginxLogParser{},
+			line:   `10.0.0.1 - - [09/Nov/2023:22:25:47 +0000] "GET / HTTP/1.1" 200 612 "-" "-"`,
+			want:   LogEntry{Level: model.Info, Message: "GET / HTTP/1.1 200"},
+			wantOk: true,
+		},
+		{
+			name:   "nginx error log",
+			parser: &nginxLogParser{},
+			line:   `2023/11/09 22:25:47 [crit] 29#29: *1 connect() failed`,
+			want:   LogEntry{Level: model.Fatal, Message: "*1 connect() failed"},
+			wantOk: true,
+		},
+		{
+			name:   "nginx error log unknown level",
+			parser: &nginxLogParser{},
+			line:   `2023/11/09 22:25:47 [custom] 29#29: message`,
+			want:   LogEntry{Level: "custom", Message: "message"},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.parser.Parse([]byte(tt.line))
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("%s.Parse(%q) = %+v, %v, want %+v, %v", tt.parser.Name(), tt.line, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestContainerLogParserDetection(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{
+			name:  "detected by votes",
+			lines: repeatLine(`{"level":"info","msg":"ok"}`, parserDetectionVotes),
+			want:  "json",
+		},
+		{
+			name:  "most votes on finish",
+			lines: append(repeatLine(`level=info msg=ok`, 3), `E1018 12:00:00.000000 1 main.go:10] failed`),
+			want:  "logfmt",
+		},
+		{
+			name:  "tie is broken by name",
+			lines: []string{`level=info msg=ok`, `E1018 12:00:00.000000 1 main.go:10] failed`},
+			want:  "klog",
+		},
+		{
+			name:  "nothing recognised",
+			lines: []string{`plain text`, `another line`},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newContainerLogParser(AutoLogParser)
+			for _, line := range tt.lines {
+				parser.parse([]byte(line))
+			}
+			parser.finishDetection()
+			if got := parser.name(); got != tt.want {
+				t.Errorf("detected parser = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func repeatLine(line string, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = line
+	}
+	return lines
+}
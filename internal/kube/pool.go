@@ -0,0 +1,90 @@
+package kube
+
+import (
+	"github.com/sirupsen/logrus"
+	"runtime/debug"
+	"sync"
+)
+
+// defaultScanWorkers is the number of pods scanned at the same time when config doesn't set it
+const defaultScanWorkers = 10
+
+// scanPool is global pool of workers which scan pods logs.
+//
+//	Every namespace scan has its own queue of tasks and workers take tasks from queues by turns,
+//	so namespace with hundreds of pods doesn't starve small namespaces
+type scanPool struct {
+	logger *logrus.Entry
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	queues [][]func()
+	next   int // index of queue which task is taken next
+}
+
+// newScanPool starts workers, they live as long as the application
+func newScanPool(workers int, logger *logrus.Entry) *scanPool {
+	sp := &scanPool{logger: logger}
+	sp.cond = sync.NewCond(&sp.mutex)
+	for i := 0; i < workers; i++ {
+		go sp.work()
+	}
+	return sp
+}
+
+// run schedules tasks of the single namespace scan and waits until all of them are done
+func (sp *scanPool) run(tasks []func()) {
+	if len(tasks) == 0 {
+		return
+	}
+	wg := sync.WaitGroup{}
+	wg.Add(len(tasks))
+	queue := make([]func(), 0, len(tasks))
+	for _, task := range tasks {
+		task := task
+		queue = append(queue, func() {
+			defer wg.Done()
+			task()
+		})
+	}
+	sp.mutex.Lock()
+	sp.queues = append(sp.queues, queue)
+	sp.mutex.Unlock()
+	sp.cond.Broadcast()
+	wg.Wait()
+}
+
+func (sp *scanPool) work() {
+	for {
+		sp.mutex.Lock()
+		for len(sp.queues) == 0 {
+			sp.cond.Wait()
+		}
+		if sp.next >= len(sp.queues) {
+			sp.next = 0
+		}
+		queue := sp.queues[sp.next]
+		task := queue[0]
+		if len(queue) == 1 {
+			// The following queue takes place of the finished one, so next stays the same
+			sp.queues = append(sp.queues[:sp.next], sp.queues[sp.next+1:]...)
+		} else {
+			sp.queues[sp.next] = queue[1:]
+			sp.next++
+		}
+		sp.mutex.Unlock()
+		sp.runTask(task)
+	}
+}
+
+// runTask recovers panic of the task, so worker survives it. Task wrapper is already marked done by run
+func (sp *scanPool) runTask(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			sp.logger.
+				WithField("panic", r).
+				WithField("stack", string(debug.Stack())).
+				Error("Scan task panicked")
+		}
+	}()
+	task()
+}
@@ -0,0 +1,116 @@
+package kube
+
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLogger() *logrus.Entry {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logrus.NewEntry(logger)
+}
+
+// waitQueues waits until pool has n queues of not taken tasks
+func waitQueues(t *testing.T, sp *scanPool, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sp.mutex.Lock()
+		queues := len(sp.queues)
+		sp.mutex.Unlock()
+		if queues == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pool has %d queues, want %d", queues, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScanPoolFairness(t *testing.T) {
+	tests := []struct {
+		name   string
+		queues [][]string
+		want   []string
+	}{
+		{
+			name:   "queues take turns",
+			queues: [][]string{{"a1", "a2", "a3"}, {"b1", "b2"}},
+			want:   []string{"a1", "b1", "a2", "b2", "a3"},
+		},
+		{
+			name:   "small namespace isn't starved",
+			queues: [][]string{{"a1", "a2", "a3", "a4"}, {"b1"}, {"c1", "c2"}},
+			want:   []string{"a1", "b1", "c1", "a2", "c2", "a3", "a4"},
+		},
+		{
+			name:   "single queue",
+			queues: [][]string{{"a1", "a2"}},
+			want:   []string{"a1", "a2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := newScanPool(1, newTestLogger())
+			// The only worker is busy until all queues are added
+			gate := make(chan struct{})
+			busy := make(chan struct{})
+			go sp.run([]func(){func() {
+				close(busy)
+				<-gate
+			}})
+			<-busy
+			mutex := sync.Mutex{}
+			got := make([]string, 0, len(tt.want))
+			wg := sync.WaitGroup{}
+			for i, names := range tt.queues {
+				tasks := make([]func(), 0, len(names))
+				for _, name := range names {
+					name := name
+					tasks = append(tasks, func() {
+						mutex.Lock()
+						got = append(got, name)
+						mutex.Unlock()
+					})
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					sp.run(tasks)
+				}()
+				waitQueues(t, sp, i+1)
+			}
+			close(gate)
+			wg.Wait()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tasks order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanPoolPanic(t *testing.T) {
+	sp := newScanPool(1, newTestLogger())
+	done := false
+	finished := make(chan struct{})
+	go func() {
+		sp.run([]func(){
+			func() { panic("scan failed") },
+			func() { done = true },
+		})
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run() hangs after task panic")
+	}
+	if !done {
+		t.Error("task after panicked one isn't run")
+	}
+}
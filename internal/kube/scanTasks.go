@@ -0,0 +1,165 @@
+package kube
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"scan_project/internal/model"
+	"sync"
+	"time"
+)
+
+// scanTasksRetention is how long finished on-demand scans are kept in memory
+const scanTasksRetention = time.Hour
+
+// scanTasks is in-memory registry of on-demand scans
+type scanTasks struct {
+	mutex sync.Mutex
+	tasks map[string]*model.ScanTask
+}
+
+func newScanTasks() *scanTasks {
+	return &scanTasks{
+		tasks: make(map[string]*model.ScanTask),
+	}
+}
+
+// create registers a new queued scan, scans finished long ago are forgotten
+func (st *scanTasks) create(clusterName string, namespace string) (model.ScanTask, error) {
+	idBytes := make([]byte, 8)
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return model.ScanTask{}, err
+	}
+	task := &model.ScanTask{
+		ID:          hex.EncodeToString(idBytes),
+		ClusterName: clusterName,
+		Namespace:   namespace,
+		Status:      model.ScanQueued,
+		Errors:      make([]string, 0),
+		Results:     make([]model.NamespaceScanResult, 0),
+		CreatedAt:   time.Now(),
+	}
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	for id, t := range st.tasks {
+		if !t.FinishedAt.IsZero() && time.Since(t.FinishedAt) > scanTasksRetention {
+			delete(st.tasks, id)
+		}
+	}
+	st.tasks[task.ID] = task
+	return copyScanTask(task), nil
+}
+
+func (st *scanTasks) get(id string) (model.ScanTask, bool) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	task, ok := st.tasks[id]
+	if !ok {
+		return model.ScanTask{}, false
+	}
+	return copyScanTask(task), true
+}
+
+// update changes scan under lock. Unknown id (e.g. empty id of periodic scan) is ignored
+func (st *scanTasks) update(id string, change func(task *model.ScanTask)) {
+	if id == "" {
+		return
+	}
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	if task, ok := st.tasks[id]; ok {
+		change(task)
+	}
+}
+
+func copyScanTask(task *model.ScanTask) model.ScanTask {
+	taskCopy := *task
+	taskCopy.Errors = append(make([]string, 0, len(task.Errors)), task.Errors...)
+	taskCopy.Results = append(make([]model.NamespaceScanResult, 0, len(task.Results)), task.Results...)
+	return taskCopy
+}
+
+// TriggerNamespaceScan starts on-demand scan of cluster namespace. Scan status can be polled with GetScanTask
+func (ks *KubeScanner) TriggerNamespaceScan(clusterName string, namespaceName string) (*model.ScanTask, error) {
+	cluster, err := ks.storage.GetClusterByName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	namespace, err := ks.storage.GetNamespace(clusterName, namespaceName)
+	if err != nil {
+		return nil, err
+	}
+	return ks.triggerScan(*cluster, namespaceName, []model.Namespace{*namespace})
+}
+
+// TriggerClusterScan starts on-demand scan of all cluster namespaces. Scan status can be polled with GetScanTask
+func (ks *KubeScanner) TriggerClusterScan(clusterName string) (*model.ScanTask, error) {
+	cluster, err := ks.storage.GetClusterByName(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := ks.storage.GetNamespaces(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return ks.triggerScan(*cluster, "", namespaces)
+}
+
+// GetScanTask returns on-demand scan by its id
+func (ks *KubeScanner) GetScanTask(id string) (*model.ScanTask, bool) {
+	task, ok := ks.tasks.get(id)
+	if !ok {
+		return nil, false
+	}
+	return &task, true
+}
+
+// triggerScan registers scan and runs it in background. Running scan is cancelled on KubeScanner shutdown
+func (ks *KubeScanner) triggerScan(cluster model.Cluster, namespaceName string, namespaces []model.Namespace) (*model.ScanTask, error) {
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if ks.cancel == nil {
+		return nil, model.NewServerErrorByCode(model.ScannerIsNotRunning)
+	}
+	task, err := ks.tasks.create(cluster.Name, namespaceName)
+	if err != nil {
+		return nil, err
+	}
+	ks.startProcessWg.Add(1)
+	go func() {
+		defer ks.startProcessWg.Done()
+		ks.runScanTask(ks.ctx, task.ID, cluster, namespaces)
+	}()
+	return &task, nil
+}
+
+// runScanTask scans namespaces simultaneously. Scan stays queued until scan of any namespace takes its lock,
+// see scanNamespace
+func (ks *KubeScanner) runScanTask(ctx context.Context, taskID string, cluster model.Cluster, namespaces []model.Namespace) {
+	wg := sync.WaitGroup{}
+	for _, namespace := range namespaces {
+		wg.Add(1)
+		go func(ns model.Namespace) {
+			defer wg.Done()
+			result, err := ks.scanNamespace(ctx, cluster, ns, taskID)
+			ks.tasks.update(taskID, func(task *model.ScanTask) {
+				if err != nil {
+					task.Errors = append(task.Errors, fmt.Sprintf("namespace %s: %s", ns.Name, err))
+					return
+				}
+				task.Results = append(task.Results, *result)
+			})
+		}(namespace)
+	}
+	wg.Wait()
+	ks.tasks.update(taskID, func(task *model.ScanTask) {
+		task.Status = model.ScanDone
+		if len(task.Errors) != 0 {
+			task.Status = model.ScanFailed
+		}
+		task.FinishedAt = time.Now()
+	})
+	ks.logger.Debugf("On-demand scan %s of cluster %s is finished", taskID, cluster.Name)
+}
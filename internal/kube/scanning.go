@@ -2,91 +2,394 @@ package kube
 
 import (
 	"bufio"
+	"bytes"
 	"context"
-	"fmt"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"scan_project/internal/model"
-	"strings"
 	"time"
 )
 
-func (ks *KubeScanner) scanServiceLog(kubeClient *kubernetes.Clientset, pod *v1.Pod) (serviceScan *model.ServiceScan, err error) {
-	// Init pod common data into Scan struct
-	serviceScan = &model.ServiceScan{
+const (
+	defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+	maxLogLineSize             = 1024 * 1024
+	previousLogTailLines       = 50
+)
+
+// scanServiceLog scans logs of all started pod containers including init containers.
+//
+//	Pending pods are scanned too, containers which have never been started or are excluded by filter are skipped.
+//	logParser is name of LogParser used for all containers, when it's empty or AutoLogParser log format of
+//	every container is detected. Counters of containers are summed into pod counters, containers breakdown is kept in model.ServiceScan Containers
+func (ks *KubeScanner) scanServiceLog(ctx context.Context, kubeClient *kubernetes.Clientset, clusterName string, pod *v1.Pod,
+	logParser string, filter *podFilter) (*model.ServiceScan, error) {
+	serviceScan := &model.ServiceScan{
 		ServiceName:     pod.Name,
 		LogTypeCountMap: make(map[string]int),
 		Uptime:          time.Now().Sub(pod.CreationTimestamp.Time),
+		Containers:      make([]model.ContainerScan, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses)),
 	}
-	var restartCount int
-	if len(pod.Status.ContainerStatuses) != 0 {
-		restartCount = int(pod.Status.ContainerStatuses[0].RestartCount) // Use first pod container, to get restarts count
+	var (
+		lastErr          error
+		containersErrors [][]model.ErrorFingerprint
+	)
+	scanStatuses := func(statuses []v1.ContainerStatus, isInit bool) {
+		for _, status := range statuses {
+			if status.State.Waiting != nil && status.LastTerminationState.Terminated == nil {
+				continue // Container has never been started yet, so there are no logs
+			}
+			if !filter.scanContainer(status.Name) {
+				continue
+			}
+			containerScan, topErrors, err := ks.scanContainerLog(ctx, kubeClient, clusterName, pod, status, isInit, logParser)
+			if err != nil {
+				ks.logger.
+					WithField("error", err).
+					Errorf("Error occured while scanning container %s of pod %s logs", status.Name, pod.Name)
+				lastErr = err
+			}
+			// Container scan is partial when only its current log can't be read
+			if containerScan == nil {
+				continue
+			}
+			serviceScan.RestartsCount += containerScan.RestartsCount
+			serviceScan.NoneJsonLinesCount += containerScan.NoneJsonLinesCount
+			serviceScan.TotalLines += containerScan.TotalLines
+			for level, count := range containerScan.LogTypeCountMap {
+				serviceScan.LogTypeCountMap[level] += count
+			}
+			serviceScan.Containers = append(serviceScan.Containers, *containerScan)
+			containersErrors = append(containersErrors, topErrors)
+		}
+	}
+	// Kubelet of pod in Unknown phase is unreachable, so such pod is reported by its diagnostics only
+	if pod.Status.Phase != v1.PodUnknown {
+		scanStatuses(pod.Status.InitContainerStatuses, true)
+		scanStatuses(pod.Status.ContainerStatuses, false)
+	}
+	if len(serviceScan.Containers) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	serviceScan.TopErrors = MergeErrorFingerprints(containersErrors, ks.errorsTopN)
+	serviceScan.ScanFinishTime = time.Now()
+	return serviceScan, nil
+}
+
+// logPosition is timestamp of the last read container log line and number of read lines with this timestamp.
+// Several lines may have the same timestamp and SinceTime returns all of them again, so they are skipped by count
+type logPosition struct {
+	time  time.Time
+	lines int
+}
+
+// logCursor skips container log lines which were read up to position from and moves position over the new lines
+type logCursor struct {
+	from      logPosition
+	fromLines int // read lines with from timestamp
+	position  logPosition
+}
+
+func newLogCursor(from logPosition) *logCursor {
+	return &logCursor{from: from, position: from}
+}
+
+// read returns true if line with logTime wasn't read up to position the cursor started from.
+// Lines without timestamp can't be positioned, so they are always new
+func (lc *logCursor) read(logTime time.Time) bool {
+	if logTime.IsZero() {
+		return true
+	}
+	if logTime.Before(lc.from.time) {
+		return false
+	}
+	if logTime.Equal(lc.from.time) {
+		lc.fromLines++
+		if lc.fromLines <= lc.from.lines {
+			return false
+		}
+	}
+	if logTime.Equal(lc.position.time) {
+		lc.position.lines++
+	} else if logTime.After(lc.position.time) {
+		lc.position = logPosition{time: logTime, lines: 1}
+	}
+	return true
+}
+
+// scanContainerLog scans container log of the pod.
+//
+//	Only log lines written after the previous scan of the same container are fetched, counters are accumulated
+//	in KubeScanner checkpoints. When container restarts, the rest of the previous instance log is read with
+//	PodLogOptions.Previous and its tail is kept, then the new instance log is read from the beginning.
+//	Container waiting for restart (e.g. in CrashLoopBackOff) has no current instance, so only its previous log is read.
+//	If the current log can't be read, container scan with counters of the lines read so far is returned with error.
+//	Lines are assembled into multiline events (e.g. stack traces), so every event is counted once.
+//	Error and fatal events are grouped by fingerprints, the most frequent of them are returned with container scan
+func (ks *KubeScanner) scanContainerLog(ctx context.Context, kubeClient *kubernetes.Clientset, clusterName string, pod *v1.Pod,
+	status v1.ContainerStatus, isInit bool, logParser string) (*model.ContainerScan, []model.ErrorFingerprint, error) {
+	key := checkpointKey{
+		cluster:   clusterName,
+		namespace: pod.Namespace,
+		podUID:    pod.UID,
+		container: status.Name,
+	}
+	cp, ok := ks.checkpoints.getService(key)
+	if !ok {
+		cp.scan = model.ContainerScan{
+			ContainerName:   status.Name,
+			InitContainer:   isInit,
+			LogTypeCountMap: make(map[string]int),
+			CountedSince:    time.Now(),
+		}
+		cp.errors = make(errorFingerprints)
+	}
+	containerScan := cp.scan
+	containerScan.RestartsCount = int(status.RestartCount)
+	if status.LastTerminationState.Terminated != nil {
+		containerScan.LastTerminationReason = status.LastTerminationState.Terminated.Reason
+	} else if status.State.Terminated != nil {
+		containerScan.LastTerminationReason = status.State.Terminated.Reason
+	}
+	sameInstance := ok && cp.containerID == status.ContainerID
+	if sameInstance && status.State.Terminated != nil {
+		// Log of terminated container doesn't change
+		return &containerScan, cp.errors.top(ks.errorsTopN), nil
+	}
+	if logParser == "" || logParser == AutoLogParser {
+		logParser = containerScan.LogParser // Detected by the previous scans
+	}
+	parser := newContainerLogParser(logParser)
+	countEvent := func(event *logEvent) {
+		containerScan.TotalLines += len(event.lines)
+		entry, ok := ks.countLogLevel(&containerScan, parser, []byte(event.lines[0]))
+		if !ok || (entry.Level != model.Error && entry.Level != model.Fatal) {
+			return
+		}
+		message := entry.Message
+		if message == "" {
+			message = event.lines[0]
+		}
+		logTime := event.time
+		if logTime.IsZero() {
+			logTime = time.Now()
+		}
+		cp.errors.add(message, event.text(), logTime)
+	}
+	// Read the previous container instance, which wasn't read completely or wasn't read at all
+	var (
+		previousRead     bool
+		previousPosition logPosition
+	)
+	if status.RestartCount > 0 && !sameInstance {
+		// The whole previous log is read for its tail, lines read while it was the current log aren't counted again
+		tail := make([]string, 0, previousLogTailLines)
+		previousEvents := newMultilineAssembler(ks.multiline, countEvent)
+		previousCursor := newLogCursor(cp.position)
+		var err error
+		previousPosition, err = ks.streamContainerLog(ctx, kubeClient, pod, &v1.PodLogOptions{
+			Container:  status.Name,
+			Timestamps: true,
+			Previous:   true,
+		}, logPosition{}, func(logTime time.Time, logBytes []byte) {
+			if previousCursor.read(logTime) {
+				previousEvents.add(logTime, logBytes)
+			}
+			if len(tail) == previousLogTailLines {
+				tail = tail[1:]
+			}
+			tail = append(tail, string(logBytes))
+		})
+		previousEvents.flush()
+		if err != nil {
+			ks.logger.
+				WithField("error", err).
+				Warningf("Failed to get previous log of container %s of pod %s", status.Name, pod.Name)
+		} else {
+			containerScan.PreviousLogTail = tail
+			previousRead = true
+		}
+	}
+	if status.State.Running == nil && status.State.Terminated == nil {
+		// The current stream would return the previous instance again or fail, its log is already counted
+		parser.finishDetection()
+		containerScan.LogParser = parser.name()
+		if previousRead {
+			cp.containerID = status.ContainerID
+			cp.position = previousPosition
+			cp.scan = containerScan
+			ks.checkpoints.setService(key, cp)
+		}
+		return &containerScan, cp.errors.top(ks.errorsTopN), nil
+	}
+	from := cp.position
+	if !sameInstance {
+		// New container instance writes its log from scratch, counters of the previous instances are kept
+		from = logPosition{}
+	}
+	events := newMultilineAssembler(ks.multiline, countEvent)
+	position, err := ks.streamContainerLog(ctx, kubeClient, pod, &v1.PodLogOptions{
+		Container:  status.Name,
+		Timestamps: true,
+	}, from, events.add)
+	events.flush()
+	parser.finishDetection()
+	containerScan.LogParser = parser.name()
+	// Checkpoint is saved even if the current log is read partially, the next scan continues after its last line
+	cp.containerID = status.ContainerID
+	cp.position = position
+	cp.scan = containerScan
+	ks.checkpoints.setService(key, cp)
+	return &containerScan, cp.errors.top(ks.errorsTopN), err
+}
+
+// streamContainerLog calls handleLine for every container log line after from position and returns position of the last line
+//
+//	podLogOpts should have Timestamps set, SinceTime is set from from argument
+func (ks *KubeScanner) streamContainerLog(ctx context.Context, kubeClient *kubernetes.Clientset, pod *v1.Pod, podLogOpts *v1.PodLogOptions,
+	from logPosition, handleLine func(logTime time.Time, logBytes []byte)) (logPosition, error) {
+	if !from.time.IsZero() {
+		podLogOpts.SinceTime = &metav1.Time{Time: from.time}
 	}
-	serviceScan.RestartsCount = restartCount
-	// Get all pod logs
-	podLogOpts := &v1.PodLogOptions{}
 	req := kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOpts)
-	podLogsStream, err := req.Stream(context.Background())
+	podLogsStream, err := req.Stream(ctx)
 	if err != nil {
-		return nil, err
+		return from, err
 	}
 	defer podLogsStream.Close()
+	// SinceTime has seconds precision, so lines handled by the previous scan are returned again
+	cursor := newLogCursor(from)
 	scanner := bufio.NewScanner(podLogsStream)
-	linesCount := 0
+	scanner.Buffer(nil, maxLogLineSize)
 	for scanner.Scan() {
-		linesCount++
-		logBytes := scanner.Bytes()
-		foundLevelBytes := ks.servicesRegexp.FindSubmatch(logBytes)
-		if foundLevelBytes == nil {
-			serviceScan.NoneJsonLinesCount++
-			continue
-		}
-		if len(foundLevelBytes) != 1 {
-			ks.logger.
-				WithField("log", string(logBytes)).
-				Warning("Several \"level\" key founds in log")
-			continue
-		}
-		foundLevelStr := string(foundLevelBytes[0])
-		level := foundLevelStr[9 : len(foundLevelStr)-1]
-		switch level {
-		case model.Trace, model.Debug, model.Info, model.Warning, model.Error, model.Fatal:
-			serviceScan.LogTypeCountMap[level] += 1
-		default:
-			ks.logger.Warning(fmt.Sprintf("Unknown log level -- %s", level))
+		logTime, logBytes := splitLogTimestamp(scanner.Bytes())
+		if cursor.read(logTime) {
+			handleLine(logTime, logBytes)
 		}
 	}
-	serviceScan.TotalLines = linesCount
-	serviceScan.ScanFinishTime = time.Now()
-	return serviceScan, nil
+	return cursor.position, scanner.Err()
 }
 
-func (ks *KubeScanner) scanJobLog(kubeClient *kubernetes.Clientset, pod *v1.Pod) (*model.JobScan, error) {
+// countLogLevel parses the first line of log event and increments corresponding container scan counter.
+//
+//	Unknown log levels are counted under their raw names. Returned entry has normalised level
+func (ks *KubeScanner) countLogLevel(containerScan *model.ContainerScan, parser *containerLogParser, logBytes []byte) (LogEntry, bool) {
+	entry, ok := parser.parse(logBytes)
+	if !ok {
+		containerScan.NoneJsonLinesCount++
+		return entry, false
+	}
+	level, ok := ks.levels.normalize(entry.Level)
+	if !ok {
+		ks.logger.Debugf("Unknown log level -- %s", level)
+	}
+	entry.Level = level
+	containerScan.LogTypeCountMap[level] += 1
+	return entry, true
+}
+
+// scanJobLog scans container log of the completed pod by grep rules. Multiline events matched by rules are returned whole.
+//
+//	Log of completed pod doesn't change, so pod is scanned only once and then the scan is taken from checkpoints.
+//	When grep rules are changed, the stored log is grepped again instead of reading it from kubernetes.
+//	The log read from kubernetes is compressed and limited by job log max size, it's returned as unsavedJobLog
+//	which should be saved by saveJobLog when the whole scan succeeds
+func (ks *KubeScanner) scanJobLog(ctx context.Context, kubeClient *kubernetes.Clientset, clusterName string, pod *v1.Pod,
+	container string, grepRules *grepRuleSet) (*model.JobScan, *unsavedJobLog, error) {
+	key := checkpointKey{
+		cluster:   clusterName,
+		namespace: pod.Namespace,
+		podUID:    pod.UID,
+		container: container,
+	}
+	if jobScan, ok := ks.checkpoints.getJob(key); ok {
+		jobScan.Age = time.Now().Sub(pod.CreationTimestamp.Time)
+		if jobScan.GrepVersion == grepRules.version {
+			return &jobScan, nil, nil
+		}
+		err := ks.regrepJobScan(clusterName, pod.Namespace, grepRules, &jobScan)
+		if err == nil {
+			ks.checkpoints.setJob(key, jobScan)
+			return &jobScan, nil, nil
+		}
+		ks.logger.
+			WithField("error", err).
+			Warningf("Failed to grep stored log of pod %s, it will be read again", pod.Name)
+	}
 	// Get all pod logs
-	podLogOpts := &v1.PodLogOptions{}
+	podLogOpts := &v1.PodLogOptions{Container: container}
 	req := kubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, podLogOpts)
-	podLogsStream, err := req.Stream(context.Background())
+	podLogsStream, err := req.Stream(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer podLogsStream.Close()
-	var sb strings.Builder
-	matchedLogRows := make([]string, 0)
+	logBuffer := newJobLogBuffer(ks.jobLogMaxSize)
+	grep := newLogGrep(grepRules.rules, ks.multiline)
 	scanner := bufio.NewScanner(podLogsStream)
+	scanner.Buffer(nil, maxLogLineSize)
 	for scanner.Scan() {
-		strokeText := scanner.Text()
-		if ks.jobsRegexp.MatchString(strokeText) {
-			matchedLogRows = append(matchedLogRows, strokeText)
-		}
-		sb.WriteString(scanner.Text())
-		sb.WriteRune('\n')
+		grep.addLine(scanner.Bytes())
+		logBuffer.addLine(scanner.Bytes())
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, err
 	}
-	return &model.JobScan{
+	grep.finish()
+	jobLog, err := logBuffer.jobLog(pod.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	jobScan := model.JobScan{
 		JobName:        pod.Name,
 		Age:            time.Now().Sub(pod.CreationTimestamp.Time),
-		FullLog:        sb.String(),
-		GrepPattern:    *ks.jobsRegexp,
-		GrepLog:        matchedLogRows,
+		LogSize:        jobLog.OriginalSize,
+		LogTruncated:   jobLog.Truncated,
 		ScanFinishTime: time.Now(),
-	}, nil
+	}
+	grepRules.setGrepResult(&jobScan, grep)
+	return &jobScan, &unsavedJobLog{key: key, jobScan: jobScan, jobLog: jobLog}, nil
+}
+
+// unsavedJobLog is job log read from kubernetes by scan with the job scan made of it
+type unsavedJobLog struct {
+	key     checkpointKey
+	jobScan model.JobScan
+	jobLog  *model.JobLog
+}
+
+// saveJobLog saves job log and checkpoints its scan. When log can't be saved, it's read again by the next scan
+func (ks *KubeScanner) saveJobLog(unsaved *unsavedJobLog) {
+	err := ks.storage.SaveJobLog(unsaved.key.cluster, unsaved.key.namespace, unsaved.jobLog)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Warningf("Failed to save log of job %s", unsaved.jobLog.JobName)
+		return
+	}
+	ks.checkpoints.setJob(unsaved.key, unsaved.jobScan)
+}
+
+// defaultContainerName returns container which logs are shown by kubectl when container is not specified
+func defaultContainerName(pod *v1.Pod) string {
+	if name, ok := pod.Annotations[defaultContainerAnnotation]; ok && name != "" {
+		return name
+	}
+	if len(pod.Spec.Containers) != 0 {
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
+
+// splitLogTimestamp separates timestamp which kubernetes adds to log line when PodLogOptions.Timestamps is set
+func splitLogTimestamp(line []byte) (time.Time, []byte) {
+	idx := bytes.IndexByte(line, ' ')
+	if idx < 0 {
+		return time.Time{}, line
+	}
+	logTime, err := time.Parse(time.RFC3339Nano, string(line[:idx]))
+	if err != nil {
+		return time.Time{}, line
+	}
+	return logTime, line[idx+1:]
 }
@@ -0,0 +1,256 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"net/http/httptest"
+	"scan_project/internal/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testLogStart = time.Date(2023, 11, 9, 22, 0, 0, 0, time.UTC)
+
+// testLogLine is container log line with timestamp added by kubernetes
+func testLogLine(second int, nanosecond int, text string) string {
+	return testLogStart.Add(time.Duration(second)*time.Second+time.Duration(nanosecond)).Format(time.RFC3339Nano) + " " + text
+}
+
+func TestLogCursor(t *testing.T) {
+	at := func(second int, nanosecond int) time.Time {
+		return testLogStart.Add(time.Duration(second)*time.Second + time.Duration(nanosecond))
+	}
+	tests := []struct {
+		name         string
+		from         logPosition
+		lines        []time.Time
+		want         []bool
+		wantPosition logPosition
+	}{
+		{
+			name:         "from the beginning",
+			lines:        []time.Time{at(0, 0), at(1, 0), at(1, 0)},
+			want:         []bool{true, true, true},
+			wantPosition: logPosition{time: at(1, 0), lines: 2},
+		},
+		{
+			name:         "lines up to position are skipped",
+			from:         logPosition{time: at(1, 5), lines: 1},
+			lines:        []time.Time{at(1, 0), at(1, 5), at(1, 6)},
+			want:         []bool{false, false, true},
+			wantPosition: logPosition{time: at(1, 6), lines: 1},
+		},
+		{
+			name:         "new lines with the same timestamp are read",
+			from:         logPosition{time: at(1, 0), lines: 2},
+			lines:        []time.Time{at(1, 0), at(1, 0), at(1, 0)},
+			want:         []bool{false, false, true},
+			wantPosition: logPosition{time: at(1, 0), lines: 3},
+		},
+		{
+			name:         "lines without timestamp are always read",
+			from:         logPosition{time: at(1, 0), lines: 1},
+			lines:        []time.Time{{}, at(1, 0)},
+			want:         []bool{true, false},
+			wantPosition: logPosition{time: at(1, 0), lines: 1},
+		},
+		{
+			name:         "nothing new",
+			from:         logPosition{time: at(2, 0), lines: 1},
+			lines:        []time.Time{at(1, 0), at(2, 0)},
+			want:         []bool{false, false},
+			wantPosition: logPosition{time: at(2, 0), lines: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := newLogCursor(tt.from)
+			for i, logTime := range tt.lines {
+				if got := cursor.read(logTime); got != tt.want[i] {
+					t.Errorf("read(line %d) = %v, want %v", i, got, tt.want[i])
+				}
+			}
+			if !cursor.position.time.Equal(tt.wantPosition.time) || cursor.position.lines != tt.wantPosition.lines {
+				t.Errorf("position = %+v, want %+v", cursor.position, tt.wantPosition)
+			}
+		})
+	}
+}
+
+// checkpointsStorageStub keeps saved checkpoints, methods which aren't overridden panic
+type checkpointsStorageStub struct {
+	StorageI
+	checkpoints map[string]model.Checkpoint
+}
+
+func (css *checkpointsStorageStub) GetCheckpoints(clusterName string, namespace string) ([]model.Checkpoint, error) {
+	checkpoints := make([]model.Checkpoint, 0, len(css.checkpoints))
+	for _, checkpoint := range css.checkpoints {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, nil
+}
+
+func (css *checkpointsStorageStub) SaveCheckpoints(clusterName string, namespace string, checkpoints []model.Checkpoint,
+	podsUIDs []string) error {
+	for _, checkpoint := range checkpoints {
+		css.checkpoints[checkpoint.PodUID+"/"+checkpoint.Container+"/"+checkpoint.Kind] = checkpoint
+	}
+	return nil
+}
+
+// containerLogsServer serves logs of the current and the previous container instances like kubelet does:
+// SinceTime is applied with seconds precision
+type containerLogsServer struct {
+	mutex    sync.Mutex
+	current  []string
+	previous []string
+}
+
+func (cls *containerLogsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cls.mutex.Lock()
+	defer cls.mutex.Unlock()
+	lines := cls.current
+	if r.URL.Query().Get("previous") == "true" {
+		lines = cls.previous
+	}
+	var since time.Time
+	if sinceTime := r.URL.Query().Get("sinceTime"); sinceTime != "" {
+		since, _ = time.Parse(time.RFC3339, sinceTime)
+	}
+	for _, line := range lines {
+		logTime, _ := splitLogTimestamp([]byte(line))
+		if logTime.Truncate(time.Second).Before(since) {
+			continue
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func newTestKubeScanner(storage StorageI) *KubeScanner {
+	logger := newTestLogger()
+	multiline, _ := newMultilineConfig(nil, "")
+	return &KubeScanner{
+		storage:     storage,
+		logger:      logger,
+		checkpoints: newCheckpoints(),
+		levels:      newLevelNormalizer(nil, logger),
+		errorsTopN:  defaultErrorsTopN,
+		multiline:   multiline,
+	}
+}
+
+func TestScanContainerLog(t *testing.T) {
+	type step struct {
+		containerID    string
+		restarts       int32
+		previous       []string
+		current        []string
+		restartScanner bool // checkpoints are saved and scanner is restarted before the step
+		wantLines      int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "only new lines are counted",
+			steps: []step{
+				{containerID: "1", current: []string{testLogLine(0, 0, "a"), testLogLine(1, 0, "b")}, wantLines: 2},
+				{
+					containerID: "1",
+					current:     []string{testLogLine(0, 0, "a"), testLogLine(1, 0, "b"), testLogLine(1, 500, "c")},
+					wantLines:   3,
+				},
+			},
+		},
+		{
+			name: "lines with the same timestamp aren't lost",
+			steps: []step{
+				{containerID: "1", current: []string{testLogLine(1, 0, "a"), testLogLine(1, 0, "b")}, wantLines: 2},
+				{
+					containerID: "1",
+					current:     []string{testLogLine(1, 0, "a"), testLogLine(1, 0, "b"), testLogLine(1, 0, "c")},
+					wantLines:   3,
+				},
+			},
+		},
+		{
+			name: "rest of the previous instance is counted after restart",
+			steps: []step{
+				{containerID: "1", current: []string{testLogLine(0, 0, "a"), testLogLine(1, 0, "b")}, wantLines: 2},
+				{
+					containerID: "2",
+					restarts:    1,
+					previous:    []string{testLogLine(0, 0, "a"), testLogLine(1, 0, "b"), testLogLine(2, 0, "c")},
+					current:     []string{testLogLine(3, 0, "d")},
+					wantLines:   4,
+				},
+				{containerID: "2", restarts: 1, current: []string{testLogLine(3, 0, "d")}, wantLines: 4},
+			},
+		},
+		{
+			name: "counting continues after scanner restart",
+			steps: []step{
+				{containerID: "1", current: []string{testLogLine(1, 0, "a"), testLogLine(1, 0, "b")}, wantLines: 2},
+				{
+					// Lines counted before restart were rotated by kubelet
+					containerID:    "1",
+					current:        []string{testLogLine(2, 0, "c"), testLogLine(3, 0, "d")},
+					restartScanner: true,
+					wantLines:      4,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := &containerLogsServer{}
+			server := httptest.NewServer(logs)
+			defer server.Close()
+			kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", UID: types.UID("uid")}}
+			storage := &checkpointsStorageStub{checkpoints: make(map[string]model.Checkpoint)}
+			ks := newTestKubeScanner(storage)
+			for i, s := range tt.steps {
+				if s.restartScanner {
+					err = ks.checkpoints.save("test", "default", map[types.UID]struct{}{pod.UID: {}}, storage)
+					if err != nil {
+						t.Fatalf("step %d: checkpoints can't be saved: %v", i, err)
+					}
+					ks = newTestKubeScanner(storage)
+					err = ks.checkpoints.load("test", "default", storage)
+					if err != nil {
+						t.Fatalf("step %d: checkpoints can't be loaded: %v", i, err)
+					}
+				}
+				logs.mutex.Lock()
+				logs.current, logs.previous = s.current, s.previous
+				logs.mutex.Unlock()
+				status := v1.ContainerStatus{
+					Name:         "main",
+					ContainerID:  s.containerID,
+					RestartCount: s.restarts,
+					State:        v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+				}
+				scan, _, err := ks.scanContainerLog(context.Background(), kubeClient, "test", pod, status, false, "")
+				if err != nil {
+					t.Fatalf("step %d: scanContainerLog() error = %v", i, err)
+				}
+				if scan.TotalLines != s.wantLines {
+					t.Errorf("step %d: total lines = %d, want %d", i, scan.TotalLines, s.wantLines)
+				}
+			}
+		})
+	}
+}
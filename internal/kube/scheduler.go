@@ -0,0 +1,205 @@
+package kube
+
+import (
+	"context"
+	"github.com/robfig/cron/v3"
+	"scan_project/internal/model"
+	"sync"
+	"time"
+)
+
+// schedulerTick is how often schedules are reloaded from storage and due scans are started
+const schedulerTick = 10 * time.Second
+
+// scheduledScan is schedule state of the single namespace
+type scheduledScan struct {
+	schedule  model.Schedule // effective schedule, next is calculated by it
+	cron      cron.Schedule
+	lastStart time.Time
+	next      time.Time
+	running   bool
+}
+
+// scheduler keeps scans schedules of all namespaces by cluster/namespace keys
+type scheduler struct {
+	mutex sync.Mutex
+	scans map[string]*scheduledScan
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		scans: make(map[string]*scheduledScan),
+	}
+}
+
+// ValidateSchedule checks that only one of interval and cron expression is set and cron expression can be parsed
+func ValidateSchedule(schedule model.Schedule) error {
+	if schedule.Interval < 0 || (schedule.Interval > 0 && schedule.Cron != "") {
+		return model.NewServerErrorByCode(model.InvalidSchedule)
+	}
+	if schedule.Cron != "" {
+		if _, err := cron.ParseStandard(schedule.Cron); err != nil {
+			return model.NewServerErrorByCode(model.InvalidSchedule)
+		}
+	}
+	return nil
+}
+
+// effectiveSchedule resolves inherited schedule of namespace: namespace -> cluster -> defaultInterval
+func effectiveSchedule(cluster model.Cluster, namespace model.Namespace, defaultInterval time.Duration) model.Schedule {
+	schedule := model.Schedule{Interval: int(defaultInterval / time.Second)}
+	switch {
+	case namespace.Schedule.Interval > 0 || namespace.Schedule.Cron != "":
+		schedule = namespace.Schedule
+	case cluster.Schedule.Interval > 0 || cluster.Schedule.Cron != "":
+		schedule = cluster.Schedule
+	}
+	schedule.Paused = namespace.Schedule.Paused || cluster.Schedule.Paused
+	return schedule
+}
+
+// runScheduler starts scans of namespaces when they are due until ctx is cancelled
+func (ks *KubeScanner) runScheduler(ctx context.Context, defaultInterval time.Duration) {
+	ks.startDueScans(ctx, defaultInterval)
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ks.startDueScans(ctx, defaultInterval)
+		}
+	}
+}
+
+// startDueScans discovers clusters namespaces, reloads their schedules and starts scans which are due.
+//
+//	Namespace is not scanned again until its previous scan is finished. Namespace without previous scans is scanned immediately
+func (ks *KubeScanner) startDueScans(ctx context.Context, defaultInterval time.Duration) {
+	clusters, err := ks.storage.GetAllClusters()
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Error("failed to get clusters from DB")
+		return
+	}
+	clustersNames := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		clustersNames = append(clustersNames, cluster.Name)
+	}
+	ks.clients.retain(clustersNames)
+	ks.discoveries.retain(clustersNames)
+	alive := make(map[string]struct{})
+	for _, cluster := range clusters {
+		// Namespaces which were discovered before are scanned if discovery fails
+		err := ks.discoverNamespaces(ctx, cluster)
+		if err != nil {
+			ks.logger.
+				WithField("error", err).
+				Errorf("Failed to discover namespaces of cluster %s", cluster.Name)
+		}
+		namespaces, err := ks.storage.GetNamespaces(cluster.Name)
+		if err != nil {
+			ks.logger.
+				WithField("error", err).
+				Errorf("Failed to get namespaces of cluster %s from DB", cluster.Name)
+			// Keep schedules of cluster namespaces, they are known again on the next tick
+			for _, namespace := range cluster.Namespaces {
+				alive[cluster.Name+"/"+namespace] = struct{}{}
+			}
+			for _, namespace := range cluster.DiscoveredNamespaces {
+				alive[cluster.Name+"/"+namespace] = struct{}{}
+			}
+			continue
+		}
+		ks.clients.retainNamespaces(cluster.Name, namespaces)
+		for _, namespace := range namespaces {
+			key := cluster.Name + "/" + namespace.Name
+			alive[key] = struct{}{}
+			schedule := effectiveSchedule(cluster, namespace, defaultInterval)
+			if !ks.scheduler.due(key, schedule) {
+				continue
+			}
+			ks.startProcessWg.Add(1)
+			go func(cluster model.Cluster, namespace model.Namespace, key string) {
+				defer ks.startProcessWg.Done()
+				defer ks.scheduler.finish(key)
+				ks.logger.Tracef("Start scanning namespace %s in cluster %s", namespace.Name, cluster.Name)
+				err := ks.ScanNamespace(ctx, cluster, namespace)
+				if err != nil {
+					ks.logger.
+						WithField("error", err).
+						Errorf("Failed to scan namespace %s in cluster %s", namespace.Name, cluster.Name)
+				} else {
+					ks.logger.Tracef("Successfully scanned namespace %s in cluster %s", namespace.Name, cluster.Name)
+				}
+			}(cluster, namespace, key)
+		}
+	}
+	ks.scheduler.retain(alive)
+}
+
+// due checks if namespace scan should be started now and marks it running.
+//
+//	When schedule is changed, the next scan time is recalculated from the last scan start
+func (s *scheduler) due(key string, schedule model.Schedule) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	scan, ok := s.scans[key]
+	if !ok {
+		scan = &scheduledScan{}
+		s.scans[key] = scan
+	}
+	if !ok || scan.schedule != schedule {
+		scan.schedule = schedule
+		scan.cron = nil
+		if schedule.Cron != "" {
+			cronSchedule, err := cron.ParseStandard(schedule.Cron)
+			if err != nil {
+				// Invalid cron can't be saved by API, so such schedule is treated as paused
+				scan.schedule.Paused = true
+			}
+			scan.cron = cronSchedule
+		}
+		scan.next = scan.nextAfter(scan.lastStart)
+	}
+	now := time.Now()
+	if scan.schedule.Paused || scan.running || now.Before(scan.next) {
+		return false
+	}
+	scan.running = true
+	scan.lastStart = now
+	scan.next = scan.nextAfter(now)
+	return true
+}
+
+// nextAfter returns time of the next scan after the scan started at lastStart, zero lastStart means that scan is due now
+func (ss *scheduledScan) nextAfter(lastStart time.Time) time.Time {
+	if lastStart.IsZero() {
+		return lastStart
+	}
+	if ss.cron != nil {
+		return ss.cron.Next(lastStart)
+	}
+	return lastStart.Add(time.Duration(ss.schedule.Interval) * time.Second)
+}
+
+func (s *scheduler) finish(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if scan, ok := s.scans[key]; ok {
+		scan.running = false
+	}
+}
+
+// retain forgets schedules of namespaces which are not in alive. Running scans are kept until they are finished
+func (s *scheduler) retain(alive map[string]struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, scan := range s.scans {
+		if _, ok := alive[key]; !ok && !scan.running {
+			delete(s.scans, key)
+		}
+	}
+}
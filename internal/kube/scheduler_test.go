@@ -0,0 +1,96 @@
+package kube
+
+import (
+	"scan_project/internal/model"
+	"testing"
+	"time"
+)
+
+func TestSchedulerDue(t *testing.T) {
+	const key = "test/default"
+	minute := model.Schedule{Interval: 60}
+	// rewind moves the last scan back in time as if it was started ago
+	rewind := func(s *scheduler, ago time.Duration) {
+		s.scans[key].lastStart = s.scans[key].lastStart.Add(-ago)
+		s.scans[key].next = s.scans[key].next.Add(-ago)
+	}
+	tests := []struct {
+		name     string
+		prepare  func(s *scheduler)
+		schedule model.Schedule
+		want     bool
+	}{
+		{
+			name:     "new namespace is scanned immediately",
+			schedule: minute,
+			want:     true,
+		},
+		{
+			name: "running scan isn't started again",
+			prepare: func(s *scheduler) {
+				s.due(key, minute)
+			},
+			schedule: minute,
+			want:     false,
+		},
+		{
+			name: "next scan waits for interval",
+			prepare: func(s *scheduler) {
+				s.due(key, minute)
+				s.finish(key)
+			},
+			schedule: minute,
+			want:     false,
+		},
+		{
+			name: "interval is passed",
+			prepare: func(s *scheduler) {
+				s.due(key, minute)
+				s.finish(key)
+				rewind(s, 2*time.Minute)
+			},
+			schedule: minute,
+			want:     true,
+		},
+		{
+			name: "changed schedule is counted from the last scan",
+			prepare: func(s *scheduler) {
+				s.due(key, model.Schedule{Interval: 3600})
+				s.finish(key)
+				rewind(s, 2*time.Minute)
+			},
+			schedule: minute,
+			want:     true,
+		},
+		{
+			name: "cron schedule waits for the next time",
+			prepare: func(s *scheduler) {
+				s.due(key, model.Schedule{Cron: "0 0 1 1 *"})
+				s.finish(key)
+			},
+			schedule: model.Schedule{Cron: "0 0 1 1 *"},
+			want:     false,
+		},
+		{
+			name:     "paused",
+			schedule: model.Schedule{Interval: 60, Paused: true},
+			want:     false,
+		},
+		{
+			name:     "invalid cron is paused",
+			schedule: model.Schedule{Cron: "every minute"},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler()
+			if tt.prepare != nil {
+				tt.prepare(s)
+			}
+			if got := s.due(key, tt.schedule); got != tt.want {
+				t.Errorf("due() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"scan_project/internal/model"
+	"sort"
+)
+
+// requiredPermissions are RBAC permissions which scanner needs in every scanned namespace, resources are read
+// by informers and pods logs are read directly
+var requiredPermissions = []model.PermissionCheck{
+	{Verb: "list", Resource: "pods"},
+	{Verb: "watch", Resource: "pods"},
+	{Verb: "get", Resource: "pods", Subresource: "log"},
+	{Verb: "list", Group: "apps", Resource: "replicasets"},
+	{Verb: "watch", Group: "apps", Resource: "replicasets"},
+	{Verb: "list", Group: "batch", Resource: "jobs"},
+	{Verb: "watch", Group: "batch", Resource: "jobs"},
+	{Verb: "list", Group: "batch", Resource: "cronjobs"},
+	{Verb: "watch", Group: "batch", Resource: "cronjobs"},
+	{Verb: "list", Resource: "events"},
+	{Verb: "watch", Resource: "events"},
+}
+
+// discoveryPermission is cluster-scoped RBAC permission which scanner needs to discover namespaces
+var discoveryPermission = model.PermissionCheck{Verb: "list", Resource: "namespaces"}
+
+// SelectKubeconfigContext makes contextName current context of kubeconfig, so the context is used by scanner
+func SelectKubeconfigContext(kubeconfig string, contextName string) (string, error) {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return "", invalidClusterConfig(fmt.Sprintf("kubeconfig can't be parsed: %s", err))
+	}
+	if _, ok := config.Contexts[contextName]; !ok {
+		return "", invalidClusterConfig(fmt.Sprintf("no context %s in kubeconfig", contextName))
+	}
+	config.CurrentContext = contextName
+	configBytes, err := clientcmd.Write(*config)
+	if err != nil {
+		return "", err
+	}
+	return string(configBytes), nil
+}
+
+// ValidateKubeconfig checks that kubeconfig auth can be used to build kubernetes client, it doesn't connect to cluster.
+// Other auth types aren't checked, they depend on scanner host and are checked by ValidateCluster only
+func ValidateKubeconfig(auth model.ClusterAuth) error {
+	if auth.AuthType != "" && auth.AuthType != model.KubeconfigAuth {
+		return nil
+	}
+	_, err := clientcmd.RESTConfigFromKubeConfig([]byte(auth.Config))
+	if err != nil {
+		return invalidClusterConfig(fmt.Sprintf("kubeconfig can't be used: %s", err))
+	}
+	return nil
+}
+
+// ValidateCluster checks that kubeconfig can be parsed, API server is reachable with cluster auth and
+// scanner has required permissions in namespaces. Permission to list namespaces is always reported, but it's required
+// only when discovery is enabled. Report is returned even if checks fail, its Error describes the first failure
+func (ks *KubeScanner) ValidateCluster(ctx context.Context, auth model.ClusterAuth, namespaces []string,
+	discovery model.NamespaceDiscovery) *model.ClusterValidation {
+	report := &model.ClusterValidation{
+		Contexts:           make([]string, 0),
+		ClusterPermissions: make([]model.PermissionCheck, 0, 1),
+		Namespaces:         make([]model.NamespaceAccess, 0, len(namespaces)),
+	}
+	if auth.AuthType == "" || auth.AuthType == model.KubeconfigAuth {
+		config, err := clientcmd.Load([]byte(auth.Config))
+		if err != nil {
+			report.Error = fmt.Sprintf("kubeconfig can't be parsed: %s", err)
+			return report
+		}
+		for name := range config.Contexts {
+			report.Contexts = append(report.Contexts, name)
+		}
+		sort.Strings(report.Contexts)
+		report.CurrentContext = config.CurrentContext
+	}
+	kubeRest, err := restConfig(auth, ks.clients.credentialsDir)
+	if err != nil {
+		report.Error = fmt.Sprintf("kubernetes client config can't be built: %s", err)
+		return report
+	}
+	report.Server = kubeRest.Host
+	kubeRest.Timeout = ks.clients.kubernetesTimeout
+	kubeClient, err := kubernetes.NewForConfig(kubeRest)
+	if err != nil {
+		report.Error = fmt.Sprintf("kubernetes client can't be created: %s", err)
+		return report
+	}
+	versionBytes, err := kubeClient.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		report.Error = fmt.Sprintf("API server is unreachable: %s", err)
+		return report
+	}
+	var serverVersion version.Info
+	err = json.Unmarshal(versionBytes, &serverVersion)
+	if err != nil {
+		report.Error = fmt.Sprintf("API server version can't be parsed: %s", err)
+		return report
+	}
+	report.ServerVersion = serverVersion.GitVersion
+	permission, err := checkPermission(ctx, kubeClient, "", discoveryPermission)
+	if err != nil {
+		report.Error = fmt.Sprintf("permissions can't be checked: %s", err)
+		return report
+	}
+	if !permission.Allowed && discoveryEnabled(discovery) {
+		report.Error = fmt.Sprintf("no permission to %s %s in cluster, it's required by namespaces discovery",
+			permission.Verb, permissionResource(permission))
+	}
+	report.ClusterPermissions = append(report.ClusterPermissions, permission)
+	for _, namespace := range namespaces {
+		access := model.NamespaceAccess{
+			Namespace:   namespace,
+			Permissions: make([]model.PermissionCheck, 0, len(requiredPermissions)),
+		}
+		for _, permission := range requiredPermissions {
+			permission, err = checkPermission(ctx, kubeClient, namespace, permission)
+			if err != nil {
+				report.Error = fmt.Sprintf("permissions can't be checked: %s", err)
+				return report
+			}
+			if !permission.Allowed && report.Error == "" {
+				report.Error = fmt.Sprintf("no permission to %s %s in namespace %s", permission.Verb,
+					permissionResource(permission), namespace)
+			}
+			access.Permissions = append(access.Permissions, permission)
+		}
+		report.Namespaces = append(report.Namespaces, access)
+	}
+	report.Valid = report.Error == ""
+	return report
+}
+
+// checkPermission asks API server if scanner is allowed to do permission action in namespace
+func checkPermission(ctx context.Context, kubeClient *kubernetes.Clientset, namespace string,
+	permission model.PermissionCheck) (model.PermissionCheck, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        permission.Verb,
+				Group:       permission.Group,
+				Resource:    permission.Resource,
+				Subresource: permission.Subresource,
+			},
+		},
+	}
+	review, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return permission, err
+	}
+	permission.Allowed = review.Status.Allowed
+	permission.Reason = review.Status.Reason
+	return permission, nil
+}
+
+func permissionResource(permission model.PermissionCheck) string {
+	resource := permission.Resource
+	if permission.Group != "" {
+		resource += "." + permission.Group
+	}
+	if permission.Subresource != "" {
+		resource += "/" + permission.Subresource
+	}
+	return resource
+}
+
+func invalidClusterConfig(reason string) *model.ServerError {
+	serverError := model.NewServerErrorByCode(model.InvalidClusterConfig)
+	serverError.Description += ": " + reason
+	return serverError
+}
@@ -0,0 +1,151 @@
+package kube
+
+import (
+	"context"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"scan_project/internal/model"
+	"sort"
+)
+
+// Kinds of workloads which own pods
+const (
+	PodKind         = "Pod"
+	ReplicaSetKind  = "ReplicaSet"
+	DeploymentKind  = "Deployment"
+	StatefulSetKind = "StatefulSet"
+	DaemonSetKind   = "DaemonSet"
+	JobKind         = "Job"
+	CronJobKind     = "CronJob"
+)
+
+// workloadResolver resolves the top-level workload of the pod by its owner references
+//
+//	ReplicaSets and Jobs owners are taken from informers once per namespace scan, so resolving doesn't make requests
+//	to kubernetes. Jobs are kept to describe execution of their pods, they are shared with informer cache
+type workloadResolver struct {
+	replicaSetsOwners map[string]*metav1.OwnerReference
+	jobsOwners        map[string]*metav1.OwnerReference
+	jobs              map[string]*batchv1.Job
+}
+
+// newWorkloadResolver lists ReplicaSets and Jobs of the namespace.
+//
+//	If listing fails, pods are resolved to their direct owners
+func (ks *KubeScanner) newWorkloadResolver(ctx context.Context, client *clusterClient, namespace string) *workloadResolver {
+	wr := &workloadResolver{
+		replicaSetsOwners: make(map[string]*metav1.OwnerReference),
+		jobsOwners:        make(map[string]*metav1.OwnerReference),
+		jobs:              make(map[string]*batchv1.Job),
+	}
+	replicaSets, err := client.listReplicaSets(ctx, namespace)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Warningf("Failed to list replica sets in namespace %s, pods will be grouped by replica sets", namespace)
+	} else {
+		for _, replicaSet := range replicaSets {
+			wr.replicaSetsOwners[replicaSet.Name] = metav1.GetControllerOf(replicaSet)
+		}
+	}
+	jobs, err := client.listJobs(ctx, namespace)
+	if err != nil {
+		ks.logger.
+			WithField("error", err).
+			Warningf("Failed to list jobs in namespace %s, pods will be grouped by jobs", namespace)
+	} else {
+		for _, job := range jobs {
+			wr.jobsOwners[job.Name] = metav1.GetControllerOf(job)
+			wr.jobs[job.Name] = job
+		}
+	}
+	return wr
+}
+
+// resolve returns kind and name of the top-level workload which owns the pod
+func (wr *workloadResolver) resolve(pod *v1.Pod) (kind string, name string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return PodKind, pod.Name
+	}
+	var ownersOwner *metav1.OwnerReference
+	switch owner.Kind {
+	case ReplicaSetKind:
+		ownersOwner = wr.replicaSetsOwners[owner.Name]
+	case JobKind:
+		ownersOwner = wr.jobsOwners[owner.Name]
+	}
+	if ownersOwner != nil {
+		return ownersOwner.Kind, ownersOwner.Name
+	}
+	return owner.Kind, owner.Name
+}
+
+// job returns batch/v1 Job which owns the pod, nil if pod isn't owned by Job or Job isn't found
+func (wr *workloadResolver) job(pod *v1.Pod) *batchv1.Job {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != JobKind {
+		return nil
+	}
+	return wr.jobs[owner.Name]
+}
+
+// AggregateWorkloads groups services and jobs scans by their workloads
+//
+//	Workloads are sorted by kind and name, scans of workload pods are nested into model.WorkloadScan
+func AggregateWorkloads(servicesScans []model.ServiceScan, jobsScans []model.JobScan) []model.WorkloadScan {
+	type workloadKey struct {
+		kind string
+		name string
+	}
+	workloads := make(map[workloadKey]*model.WorkloadScan)
+	getWorkload := func(kind string, name string) *model.WorkloadScan {
+		key := workloadKey{kind: kind, name: name}
+		workload, ok := workloads[key]
+		if !ok {
+			workload = &model.WorkloadScan{
+				Kind:            kind,
+				Name:            name,
+				LogTypeCountMap: make(map[string]int),
+				ServicesScans:   make([]model.ServiceScan, 0),
+				JobsScans:       make([]model.JobScan, 0),
+			}
+			workloads[key] = workload
+		}
+		return workload
+	}
+	for _, serviceScan := range servicesScans {
+		workload := getWorkload(serviceScan.WorkloadKind, serviceScan.WorkloadName)
+		workload.PodsCount++
+		workload.RestartsCount += serviceScan.RestartsCount
+		workload.NoneJsonLinesCount += serviceScan.NoneJsonLinesCount
+		workload.TotalLines += serviceScan.TotalLines
+		for level, count := range serviceScan.LogTypeCountMap {
+			workload.LogTypeCountMap[level] += count
+		}
+		workload.ServicesScans = append(workload.ServicesScans, serviceScan)
+	}
+	for _, jobScan := range jobsScans {
+		workload := getWorkload(jobScan.WorkloadKind, jobScan.WorkloadName)
+		workload.PodsCount++
+		workload.JobsScans = append(workload.JobsScans, jobScan)
+	}
+	result := make([]model.WorkloadScan, 0, len(workloads))
+	for _, workload := range workloads {
+		sort.Slice(workload.ServicesScans, func(i, j int) bool {
+			return workload.ServicesScans[i].ServiceName < workload.ServicesScans[j].ServiceName
+		})
+		sort.Slice(workload.JobsScans, func(i, j int) bool {
+			return workload.JobsScans[i].JobName < workload.JobsScans[j].JobName
+		})
+		result = append(result, *workload)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
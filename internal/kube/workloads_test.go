@@ -0,0 +1,153 @@
+package kube
+
+import (
+	"reflect"
+	"scan_project/internal/model"
+	"testing"
+)
+
+func TestAggregateWorkloads(t *testing.T) {
+	tests := []struct {
+		name          string
+		servicesScans []model.ServiceScan
+		jobsScans     []model.JobScan
+		want          []model.WorkloadScan
+	}{
+		{
+			name:          "no scans",
+			servicesScans: []model.ServiceScan{},
+			jobsScans:     []model.JobScan{},
+			want:          []model.WorkloadScan{},
+		},
+		{
+			name: "counters of workload pods are summed",
+			servicesScans: []model.ServiceScan{
+				{
+					ServiceName:        "api-2",
+					WorkloadKind:       "Deployment",
+					WorkloadName:       "api",
+					RestartsCount:      1,
+					LogTypeCountMap:    map[string]int{"error": 2, "info": 3},
+					NoneJsonLinesCount: 1,
+					TotalLines:         6,
+				},
+				{
+					ServiceName:     "api-1",
+					WorkloadKind:    "Deployment",
+					WorkloadName:    "api",
+					RestartsCount:   2,
+					LogTypeCountMap: map[string]int{"info": 4},
+					TotalLines:      4,
+				},
+			},
+			want: []model.WorkloadScan{
+				{
+					Kind:               "Deployment",
+					Name:               "api",
+					PodsCount:          2,
+					RestartsCount:      3,
+					LogTypeCountMap:    map[string]int{"error": 2, "info": 7},
+					NoneJsonLinesCount: 1,
+					TotalLines:         10,
+					ServicesScans: []model.ServiceScan{
+						{
+							ServiceName:     "api-1",
+							WorkloadKind:    "Deployment",
+							WorkloadName:    "api",
+							RestartsCount:   2,
+							LogTypeCountMap: map[string]int{"info": 4},
+							TotalLines:      4,
+						},
+						{
+							ServiceName:        "api-2",
+							WorkloadKind:       "Deployment",
+							WorkloadName:       "api",
+							RestartsCount:      1,
+							LogTypeCountMap:    map[string]int{"error": 2, "info": 3},
+							NoneJsonLinesCount: 1,
+							TotalLines:         6,
+						},
+					},
+					JobsScans: []model.JobScan{},
+				},
+			},
+		},
+		{
+			name: "workloads are sorted by kind and name",
+			servicesScans: []model.ServiceScan{
+				{ServiceName: "worker-0", WorkloadKind: "StatefulSet", WorkloadName: "worker"},
+				{ServiceName: "web-1", WorkloadKind: "Deployment", WorkloadName: "web"},
+				{ServiceName: "api-1", WorkloadKind: "Deployment", WorkloadName: "api"},
+			},
+			jobsScans: []model.JobScan{
+				{JobName: "backup-2", WorkloadKind: "CronJob", WorkloadName: "backup"},
+				{JobName: "backup-1", WorkloadKind: "CronJob", WorkloadName: "backup"},
+			},
+			want: []model.WorkloadScan{
+				{
+					Kind:            "CronJob",
+					Name:            "backup",
+					PodsCount:       2,
+					LogTypeCountMap: map[string]int{},
+					ServicesScans:   []model.ServiceScan{},
+					JobsScans: []model.JobScan{
+						{JobName: "backup-1", WorkloadKind: "CronJob", WorkloadName: "backup"},
+						{JobName: "backup-2", WorkloadKind: "CronJob", WorkloadName: "backup"},
+					},
+				},
+				{
+					Kind:            "Deployment",
+					Name:            "api",
+					PodsCount:       1,
+					LogTypeCountMap: map[string]int{},
+					ServicesScans:   []model.ServiceScan{{ServiceName: "api-1", WorkloadKind: "Deployment", WorkloadName: "api"}},
+					JobsScans:       []model.JobScan{},
+				},
+				{
+					Kind:            "Deployment",
+					Name:            "web",
+					PodsCount:       1,
+					LogTypeCountMap: map[string]int{},
+					ServicesScans:   []model.ServiceScan{{ServiceName: "web-1", WorkloadKind: "Deployment", WorkloadName: "web"}},
+					JobsScans:       []model.JobScan{},
+				},
+				{
+					Kind:            "StatefulSet",
+					Name:            "worker",
+					PodsCount:       1,
+					LogTypeCountMap: map[string]int{},
+					ServicesScans: []model.ServiceScan{
+						{ServiceName: "worker-0", WorkloadKind: "StatefulSet", WorkloadName: "worker"},
+					},
+					JobsScans: []model.JobScan{},
+				},
+			},
+		},
+		{
+			name: "pods without controller",
+			servicesScans: []model.ServiceScan{
+				{ServiceName: "debug", WorkloadKind: "Pod", WorkloadName: "debug", TotalLines: 1},
+			},
+			want: []model.WorkloadScan{
+				{
+					Kind:            "Pod",
+					Name:            "debug",
+					PodsCount:       1,
+					LogTypeCountMap: map[string]int{},
+					TotalLines:      1,
+					ServicesScans: []model.ServiceScan{
+						{ServiceName: "debug", WorkloadKind: "Pod", WorkloadName: "debug", TotalLines: 1},
+					},
+					JobsScans: []model.JobScan{},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AggregateWorkloads(tt.servicesScans, tt.jobsScans); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AggregateWorkloads() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,23 +1,70 @@
 package model
 
 const (
-	InternalServerError      = 5001
-	UnknownDBError           = 5002
-	WrongFormatError         = 5003
-	NoClusterNameProvided    = 5004
-	NoNamespaceProvided      = 5005
-	NoSuchNamespaceInCluster = 5006
+	InternalServerError       = 5001
+	UnknownDBError            = 5002
+	WrongFormatError          = 5003
+	NoClusterNameProvided     = 5004
+	NoNamespaceProvided       = 5005
+	NoSuchNamespaceInCluster  = 5006
+	NoWorkloadProvided        = 5007
+	NoSuchWorkloadInNamespace = 5008
+	UnknownLogParser          = 5009
+	NoServiceProvided         = 5010
+	NoSuchServiceInNamespace  = 5011
+	NoSuchScanTask            = 5012
+	ScannerIsNotRunning       = 5013
+	InvalidSchedule           = 5014
+	InvalidPodFilters         = 5015
+	InvalidNamespaceDiscovery = 5016
+	InvalidClusterAuth        = 5017
+	InvalidClusterConfig      = 5018
+	NoJobProvided             = 5019
+	InvalidGrepRules          = 5020
+	NoSuchJobInNamespace      = 5021
 )
 
 func NewServerErrorByCode(errCode int) *ServerError {
 	var sError ServerError
 	switch errCode {
+	case WrongFormatError:
+		sError.Description = "wrong format of request parameters"
 	case NoClusterNameProvided:
 		sError.Description = "no cluster name provided in request"
 	case NoNamespaceProvided:
 		sError.Description = "no namespace provided in request"
 	case NoSuchNamespaceInCluster:
 		sError.Description = "no such namespace in cluster"
+	case NoWorkloadProvided:
+		sError.Description = "no workload kind or name provided in request"
+	case NoSuchWorkloadInNamespace:
+		sError.Description = "no such workload in namespace"
+	case UnknownLogParser:
+		sError.Description = "unknown log parser"
+	case NoServiceProvided:
+		sError.Description = "no service provided in request"
+	case NoSuchServiceInNamespace:
+		sError.Description = "no such service in namespace"
+	case NoSuchScanTask:
+		sError.Description = "no such scan"
+	case ScannerIsNotRunning:
+		sError.Description = "kube-scanner is not running"
+	case InvalidSchedule:
+		sError.Description = "invalid schedule: interval must be positive, cron must be valid and only one of them can be set"
+	case InvalidPodFilters:
+		sError.Description = "invalid pods filters: selectors or name patterns can't be parsed"
+	case InvalidNamespaceDiscovery:
+		sError.Description = "invalid namespace discovery: label selector or name patterns can't be parsed"
+	case InvalidClusterAuth:
+		sError.Description = "invalid cluster auth: unknown auth type, its required fields are empty or token files are out of credentials directory"
+	case InvalidClusterConfig:
+		sError.Description = "cluster check failed"
+	case NoJobProvided:
+		sError.Description = "no job provided in request"
+	case InvalidGrepRules:
+		sError.Description = "invalid grep rules: names must be unique, patterns must be valid, severity must be a log level and context lines must be from 0 to 20"
+	case NoSuchJobInNamespace:
+		sError.Description = "no such job in namespace"
 	default:
 		sError.Code = InternalServerError
 		sError.Description = "Unexpected error occurred"
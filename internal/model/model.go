@@ -1,7 +1,6 @@
 package model
 
 import (
-	"regexp"
 	"time"
 )
 
@@ -16,29 +15,467 @@ const (
 	Fatal   string = "fatal"
 )
 
+// Cluster is scanned kubernetes cluster. ScanStatuses are keyed by namespace, never scanned namespaces are absent.
+//
+//	Namespaces are added manually, DiscoveredNamespaces are found by Discovery rules and removed when they don't match anymore
 type Cluster struct {
-	Config     string   `json:"config"`
-	Name       string   `json:"name"`
-	Namespaces []string `json:"namespaces"`
+	ClusterAuth
+	Name                 string                `json:"name"`
+	Namespaces           []string              `json:"namespaces"`
+	DiscoveredNamespaces []string              `json:"discovered_namespaces"`
+	LogParser            string                `json:"log_parser"`
+	ScanStatuses         map[string]ScanStatus `json:"scan_statuses"`
+	Schedule             Schedule              `json:"schedule"`
+	Discovery            NamespaceDiscovery    `json:"discovery"`
+	GrepRules            []GrepRule            `json:"grep_rules"`
 }
 
+// Cluster auth types
+const (
+	KubeconfigAuth = "kubeconfig"
+	InClusterAuth  = "in_cluster"
+	TokenAuth      = "token"
+)
+
+// ClusterAuth sets how scanner connects to cluster, empty AuthType means KubeconfigAuth.
+//
+//	KubeconfigAuth uses kubeconfig stored in Config. InClusterAuth uses service account of scanner pod, so it's
+//	suitable only for cluster where scanner is deployed. TokenAuth connects to Server with token read from TokenFile
+//	on scanner host, the token is re-read when it's rotated. Empty CAFile of TokenAuth means that system roots are used
+type ClusterAuth struct {
+	AuthType  string `json:"auth_type"`
+	Config    string `json:"config"`
+	Server    string `json:"server"`
+	TokenFile string `json:"token_file"`
+	CAFile    string `json:"ca_file"`
+}
+
+// ClusterValidation is report of cluster auth check: kubeconfig parsing, API server connectivity and scanner
+// permissions in namespaces and in the whole cluster. Contexts are filled for kubeconfig auth only.
+// Error describes the first failed check
+type ClusterValidation struct {
+	Valid              bool              `json:"valid"`
+	Contexts           []string          `json:"contexts"`
+	CurrentContext     string            `json:"current_context"`
+	Server             string            `json:"server"`
+	ServerVersion      string            `json:"server_version"`
+	ClusterPermissions []PermissionCheck `json:"cluster_permissions"`
+	Namespaces         []NamespaceAccess `json:"namespaces"`
+	Error              string            `json:"error"`
+}
+
+// NamespaceAccess is result of RBAC permissions checks in namespace
+type NamespaceAccess struct {
+	Namespace   string            `json:"namespace"`
+	Permissions []PermissionCheck `json:"permissions"`
+}
+
+// PermissionCheck is result of SelfSubjectAccessReview of the single action, Reason is set by authorizer.
+// Empty Group is the core API group
+type PermissionCheck struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource"`
+	Allowed     bool   `json:"allowed"`
+	Reason      string `json:"reason"`
+}
+
+// NamespaceDiscovery sets which cluster namespaces are scanned without adding them manually, empty discovery is disabled.
+//
+//	Namespace is discovered if it matches LabelSelector and its name matches any of glob NamePatterns or NameRegexp.
+//	Empty LabelSelector matches all namespaces, as well as name rules when both of them are empty
+type NamespaceDiscovery struct {
+	NamePatterns  []string `json:"name_patterns"`
+	NameRegexp    string   `json:"name_regexp"`
+	LabelSelector string   `json:"label_selector"`
+}
+
+// Namespace is cluster namespace with its scan settings
+//
+//	Empty LogParser means that cluster log parser is used. ScanStatus is nil if namespace has never been scanned.
+//	Discovered namespace was added by cluster NamespaceDiscovery, adding it manually pins it to cluster
+type Namespace struct {
+	Name        string        `json:"name"`
+	ClusterName string        `json:"cluster_name"`
+	Discovered  bool          `json:"discovered"`
+	LogParser   string        `json:"log_parser"`
+	ScanStatus  *ScanStatus   `json:"scan_status"`
+	Schedule    Schedule      `json:"schedule"`
+	Filters     PodFilters    `json:"filters"`
+	GrepRules   []GrepRule    `json:"grep_rules"`
+	Resources   ScanResources `json:"resources"`
+}
+
+// PodFilters selects pods and containers of namespace which are scanned, empty rules match everything.
+//
+//	Pod is scanned if it matches include selector, field selector and include name pattern and doesn't match
+//	exclude selector and exclude name pattern. Name patterns are regular expressions, they aren't anchored.
+//	ExcludeContainers are names of containers which logs are never read, e.g. istio-proxy
+type PodFilters struct {
+	IncludeLabelSelector string   `json:"include_label_selector"`
+	ExcludeLabelSelector string   `json:"exclude_label_selector"`
+	FieldSelector        string   `json:"field_selector"`
+	IncludeNamePattern   string   `json:"include_name_pattern"`
+	ExcludeNamePattern   string   `json:"exclude_name_pattern"`
+	ExcludeContainers    []string `json:"exclude_containers"`
+}
+
+// ScanResources sets which namespace resources are scanned besides pods logs, all of them are scanned by default.
+//
+//	Disabled resources aren't watched by scanner, so it doesn't need permissions to list them
+type ScanResources struct {
+	DisableEvents   bool `json:"disable_events"`
+	DisableCronJobs bool `json:"disable_cronjobs"`
+}
+
+// Schedule sets how often namespaces are scanned, either Interval in seconds or Cron expression.
+//
+//	Schedule without both of them is inherited: namespace -> cluster -> global scan_delay.
+//	Paused stops scans of namespace, paused cluster stops scans of all its namespaces
+type Schedule struct {
+	Interval int    `json:"interval"`
+	Cron     string `json:"cron"`
+	Paused   bool   `json:"paused"`
+}
+
+// ScanStatus is result of the last namespace scan attempt.
+//
+//	Empty Error means that the last attempt succeeded, LastSuccessTime is zero if namespace has never been scanned successfully.
+//	Attempt which saved scans of some pods only is successful, its Error lists pods which failed to be scanned
+type ScanStatus struct {
+	LastAttemptTime time.Time     `json:"last_attempt_time"`
+	LastSuccessTime time.Time     `json:"last_success_time"`
+	Duration        time.Duration `json:"duration"`
+	PodsCount       int           `json:"pods_count"`
+	Error           string        `json:"error"`
+}
+
+// ServiceScan is result of running pod scan. Counters are summed over all pod containers
 type ServiceScan struct {
-	ServiceName        string         `json:"service_name"`
-	Uptime             time.Duration  `json:"uptime"`
+	ServiceName        string             `json:"service_name"`
+	WorkloadKind       string             `json:"workload_kind"`
+	WorkloadName       string             `json:"workload_name"`
+	Uptime             time.Duration      `json:"uptime"`
+	RestartsCount      int                `json:"restarts_count"`
+	LogTypeCountMap    map[string]int     `json:"logs_info"`
+	NoneJsonLinesCount int                `json:"none_json_lines_count"`
+	TotalLines         int                `json:"total_lines"`
+	Containers         []ContainerScan    `json:"containers"`
+	TopErrors          []ErrorFingerprint `json:"top_errors"`
+	Events             []EventScan        `json:"events"`
+	Diagnostics        PodDiagnostics     `json:"diagnostics"`
+	ScanFinishTime     time.Time          `json:"scan_finish_time"`
+}
+
+// ContainerScan is result of scan of the single pod container.
+//
+//	Counters are accumulated in scanner checkpoints since CountedSince, when the container was scanned the first time.
+//	The first scan counts the log kept by kubelet, so counters are reset only when checkpoint is lost
+type ContainerScan struct {
+	ContainerName         string         `json:"container_name"`
+	InitContainer         bool           `json:"init_container"`
+	RestartsCount         int            `json:"restarts_count"`
+	LastTerminationReason string         `json:"last_termination_reason"`
+	LogParser             string         `json:"log_parser"`
+	LogTypeCountMap       map[string]int `json:"logs_info"`
+	NoneJsonLinesCount    int            `json:"none_json_lines_count"`
+	TotalLines            int            `json:"total_lines"`
+	PreviousLogTail       []string       `json:"previous_log_tail"`
+	CountedSince          time.Time      `json:"counted_since"`
+}
+
+type JobScan struct {
+	JobName        string         `json:"job_name"`
+	WorkloadKind   string         `json:"workload_kind"`
+	WorkloadName   string         `json:"workload_name"`
+	Age            time.Duration  `json:"age"`
+	LogSize        int64          `json:"log_size"`
+	LogTruncated   bool           `json:"log_truncated"`
+	GrepRules      []GrepRuleRef  `json:"grep_rules"`
+	GrepVersion    string         `json:"grep_version"`
+	GrepLog        []string       `json:"grep_log"`
+	GrepMatches    []GrepMatch    `json:"grep_matches"`
+	Events         []EventScan    `json:"events"`
+	Diagnostics    PodDiagnostics `json:"diagnostics"`
+	Execution      JobExecution   `json:"execution"`
+	ScanFinishTime time.Time      `json:"scan_finish_time"`
+}
+
+// Statuses of job pods and batch/v1 Jobs reported in JobExecution
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// JobExecution is outcome of the job pod run.
+//
+//	ExitCode is exit code of the scanned container, it's nil when container wasn't terminated. Times are zero
+//	when they are unknown. Job is nil when pod isn't owned by batch/v1 Job or the Job is already deleted
+type JobExecution struct {
+	Status         string        `json:"status"`
+	StartTime      time.Time     `json:"start_time"`
+	CompletionTime time.Time     `json:"completion_time"`
+	Duration       time.Duration `json:"duration"`
+	ExitCode       *int          `json:"exit_code"`
+	FailureReason  string        `json:"failure_reason"`
+	FailureMessage string        `json:"failure_message"`
+	Job            *JobRun       `json:"job"`
+}
+
+// JobRun is state of batch/v1 Job which owns job pod.
+//
+//	Attempts is number of pods Job has run including active ones, Job is retried until Failed reaches BackoffLimit.
+//	FailureReason is reason of Job Failed condition, e.g. BackoffLimitExceeded or DeadlineExceeded.
+//	Duration is zero while Job is running
+type JobRun struct {
+	Name           string        `json:"name"`
+	Status         string        `json:"status"`
+	StartTime      time.Time     `json:"start_time"`
+	CompletionTime time.Time     `json:"completion_time"`
+	Duration       time.Duration `json:"duration"`
+	Attempts       int           `json:"attempts"`
+	Active         int           `json:"active"`
+	Succeeded      int           `json:"succeeded"`
+	Failed         int           `json:"failed"`
+	BackoffLimit   int           `json:"backoff_limit"`
+	FailureReason  string        `json:"failure_reason"`
+	FailureMessage string        `json:"failure_message"`
+}
+
+// CronJobScan is health of CronJob by its schedule and Jobs it has created.
+//
+//	ConsecutiveFailures is number of the latest finished Jobs which failed, only Jobs kept by CronJob history limits
+//	are counted, so it can't exceed FailedJobsHistoryLimit. ConsecutiveFailuresCapped is set when all kept failed Jobs
+//	are in the streak and older Jobs may have failed too. MissedSchedules are the latest schedule times after LastScheduleTime when Job wasn't created,
+//	MissedSchedulesCount is number of all of them. Suspended CronJob has no missed schedules
+type CronJobScan struct {
+	Name                      string      `json:"name"`
+	Schedule                  string      `json:"schedule"`
+	TimeZone                  string      `json:"time_zone"`
+	Suspended                 bool        `json:"suspended"`
+	LastScheduleTime          time.Time   `json:"last_schedule_time"`
+	LastSuccessfulTime        time.Time   `json:"last_successful_time"`
+	NextScheduleTime          time.Time   `json:"next_schedule_time"`
+	LastJobName               string      `json:"last_job_name"`
+	LastJobStatus             string      `json:"last_job_status"`
+	ActiveJobs                int         `json:"active_jobs"`
+	ConsecutiveFailures       int         `json:"consecutive_failures"`
+	FailedJobsHistoryLimit    int         `json:"failed_jobs_history_limit"`
+	ConsecutiveFailuresCapped bool        `json:"consecutive_failures_capped"`
+	MissedSchedulesCount      int         `json:"missed_schedules_count"`
+	MissedSchedules           []time.Time `json:"missed_schedules"`
+	Problems                  []string    `json:"problems"`
+	ScanFinishTime            time.Time   `json:"scan_finish_time"`
+}
+
+// GrepRule is named rule of jobs logs grep.
+//
+//	Log event matches rule if any of its lines matches Pattern and none of them matches ExcludePattern.
+//	Rules are set globally in config and for clusters and namespaces, rule of namespace replaces cluster rule
+//	with the same name and cluster rule replaces global one. ContextBefore and ContextAfter are numbers of lines
+//	around matched event returned with the match, like grep -B and -A
+type GrepRule struct {
+	Name           string `json:"name"`
+	Pattern        string `json:"pattern"`
+	ExcludePattern string `json:"exclude_pattern"`
+	Severity       string `json:"severity"`
+	Description    string `json:"description"`
+	ContextBefore  int    `json:"context_before"`
+	ContextAfter   int    `json:"context_after"`
+}
+
+// GrepRuleRef records grep rule which was applied to job log, so scans made with the previous patterns can be found
+type GrepRuleRef struct {
+	Name           string `json:"name"`
+	Pattern        string `json:"pattern"`
+	ExcludePattern string `json:"exclude_pattern"`
+}
+
+// GrepMatch is log event matched by grep rule. Line is number of the first event line in log starting from 1
+type GrepMatch struct {
+	Rule     string   `json:"rule"`
+	Severity string   `json:"severity"`
+	Line     int      `json:"line"`
+	Text     string   `json:"text"`
+	Before   []string `json:"before"`
+	After    []string `json:"after"`
+}
+
+// JobLog is log of completed pod, it's stored compressed separately from JobScan.
+//
+//	Log bigger than configured limit is stored with its beginning and end only and truncation marker between them.
+//	Size is size of the stored log, OriginalSize is size of the whole log read from kubernetes
+type JobLog struct {
+	JobName      string    `json:"job_name"`
+	Size         int64     `json:"size"`
+	OriginalSize int64     `json:"original_size"`
+	Truncated    bool      `json:"truncated"`
+	Compressed   []byte    `json:"-"`
+	SaveTime     time.Time `json:"save_time"`
+}
+
+// ErrorFingerprint is a group of error log entries which differ only by variable parts (ids, numbers, timestamps etc.)
+type ErrorFingerprint struct {
+	Fingerprint string    `json:"fingerprint"`
+	Pattern     string    `json:"pattern"`
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Sample      string    `json:"sample"`
+}
+
+// EventScan is aggregation of kubernetes events with the same involved object and reason.
+//
+//	Type and Message are taken from the latest event
+type EventScan struct {
+	InvolvedKind string    `json:"involved_kind"`
+	InvolvedName string    `json:"involved_name"`
+	Reason       string    `json:"reason"`
+	Type         string    `json:"type"`
+	Count        int       `json:"count"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Message      string    `json:"message"`
+}
+
+// PodDiagnostics describes pod health, which can't be seen from its logs.
+//
+//	Problems is human-readable summary of found issues, empty Problems means that pod is healthy
+type PodDiagnostics struct {
+	Phase           string                 `json:"phase"`
+	Reason          string                 `json:"reason"`
+	Message         string                 `json:"message"`
+	Ready           bool                   `json:"ready"`
+	PendingDuration time.Duration          `json:"pending_duration"`
+	PendingReason   string                 `json:"pending_reason"`
+	Containers      []ContainerDiagnostics `json:"containers"`
+	ProbeFailures   []ProbeFailure         `json:"probe_failures"`
+	Problems        []string               `json:"problems"`
+}
+
+// ContainerDiagnostics is state of the single pod container.
+//
+//	LastExitCode is nil when container has never been terminated
+type ContainerDiagnostics struct {
+	ContainerName         string    `json:"container_name"`
+	InitContainer         bool      `json:"init_container"`
+	Ready                 bool      `json:"ready"`
+	State                 string    `json:"state"`
+	WaitingReason         string    `json:"waiting_reason"`
+	WaitingMessage        string    `json:"waiting_message"`
+	RestartsCount         int       `json:"restarts_count"`
+	LastTerminationReason string    `json:"last_termination_reason"`
+	LastExitCode          *int      `json:"last_exit_code"`
+	LastTerminationTime   time.Time `json:"last_termination_time"`
+}
+
+// ProbeFailure is aggregation of failures of the same pod probe (liveness, readiness or startup)
+type ProbeFailure struct {
+	Probe    string    `json:"probe"`
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+	Message  string    `json:"message"`
+}
+
+// WorkloadScan is aggregation of scans of pods which belong to the same workload (Deployment, StatefulSet, CronJob etc.)
+type WorkloadScan struct {
+	Kind               string         `json:"kind"`
+	Name               string         `json:"name"`
+	PodsCount          int            `json:"pods_count"`
 	RestartsCount      int            `json:"restarts_count"`
 	LogTypeCountMap    map[string]int `json:"logs_info"`
 	NoneJsonLinesCount int            `json:"none_json_lines_count"`
 	TotalLines         int            `json:"total_lines"`
-	ScanFinishTime     time.Time      `json:"scan_finish_time"`
+	ServicesScans      []ServiceScan  `json:"services_scans"`
+	JobsScans          []JobScan      `json:"jobs_scans"`
 }
 
-type JobScan struct {
-	JobName        string        `json:"job_name"`
-	Age            time.Duration `json:"age"`
-	FullLog        string        `json:"full_log"`
-	GrepPattern    regexp.Regexp `json:"grep_pattern"`
-	GrepLog        []string      `json:"grep_log"`
-	ScanFinishTime time.Time     `json:"scan_finish_time"`
+// Statuses of on-demand scan
+const (
+	ScanQueued  = "queued"
+	ScanRunning = "running"
+	ScanDone    = "done"
+	ScanFailed  = "failed"
+)
+
+// ScanTask is on-demand scan of the cluster namespace or of all cluster namespaces.
+//
+//	Empty Namespace means that the whole cluster is scanned
+type ScanTask struct {
+	ID          string                `json:"id"`
+	ClusterName string                `json:"cluster_name"`
+	Namespace   string                `json:"namespace"`
+	Status      string                `json:"status"`
+	PodsTotal   int                   `json:"pods_total"`
+	PodsDone    int                   `json:"pods_done"`
+	Errors      []string              `json:"errors"`
+	Results     []NamespaceScanResult `json:"results"`
+	CreatedAt   time.Time             `json:"created_at"`
+	StartedAt   time.Time             `json:"started_at"`
+	FinishedAt  time.Time             `json:"finished_at"`
+}
+
+// NamespaceScanResult is summary of saved namespace scans
+type NamespaceScanResult struct {
+	Namespace     string `json:"namespace"`
+	ServicesScans int    `json:"services_scans"`
+	JobsScans     int    `json:"jobs_scans"`
+	EventsScans   int    `json:"events_scans"`
+	CronJobsScans int    `json:"cronjobs_scans"`
+}
+
+// ServicesScansRecord is a single saved run of services scans for cluster namespace, they were found by
+// every scan from ScanTime till LastScanTime
+type ServicesScansRecord struct {
+	ScanTime     time.Time     `json:"scan_time"`
+	LastScanTime time.Time     `json:"last_scan_time"`
+	Scans        []ServiceScan `json:"scans"`
+}
+
+// JobsScansRecord is a single saved run of jobs scans for cluster namespace, they were found by
+// every scan from ScanTime till LastScanTime
+type JobsScansRecord struct {
+	ScanTime     time.Time `json:"scan_time"`
+	LastScanTime time.Time `json:"last_scan_time"`
+	Scans        []JobScan `json:"scans"`
+}
+
+// Kinds of scanner checkpoints
+const (
+	ServiceCheckpoint = "service"
+	JobCheckpoint     = "job"
+)
+
+// Checkpoint is stored scanner state of pod container, so scanner continues from it after restart.
+//
+//	Service checkpoint tells that container log was read up to LogLines lines with LogTime timestamp, State is
+//	counters of the container encoded by scanner. Job checkpoint State is scan of completed pod
+type Checkpoint struct {
+	PodUID      string
+	Container   string
+	Kind        string
+	ContainerID string
+	LogTime     time.Time
+	LogLines    int
+	State       []byte
+}
+
+// EventsScansRecord is a single saved run of events scans for cluster namespace, they were found by
+// every scan from ScanTime till LastScanTime
+type EventsScansRecord struct {
+	ScanTime     time.Time   `json:"scan_time"`
+	LastScanTime time.Time   `json:"last_scan_time"`
+	Scans        []EventScan `json:"scans"`
+}
+
+// CronJobsScansRecord is a single saved run of CronJobs scans for cluster namespace, they were found by
+// every scan from ScanTime till LastScanTime
+type CronJobsScansRecord struct {
+	ScanTime     time.Time     `json:"scan_time"`
+	LastScanTime time.Time     `json:"last_scan_time"`
+	Scans        []CronJobScan `json:"scans"`
 }
 
 type CommonServiceLog struct {
@@ -18,7 +18,135 @@ CREATE TABLE if not exists kube.namespaces (
     UNIQUE (name, cluster_name)
 );
 
+ALTER TABLE kube.clusters ADD COLUMN if not exists log_parser VARCHAR(20);
+ALTER TABLE kube.namespaces ADD COLUMN if not exists log_parser VARCHAR(20);
+ALTER TABLE kube.clusters ADD COLUMN if not exists scan_interval int;
+ALTER TABLE kube.clusters ADD COLUMN if not exists scan_cron VARCHAR(100);
+ALTER TABLE kube.clusters ADD COLUMN if not exists scan_paused boolean DEFAULT false;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists scan_interval int;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists scan_cron VARCHAR(100);
+ALTER TABLE kube.namespaces ADD COLUMN if not exists scan_paused boolean DEFAULT false;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists include_label_selector VARCHAR;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists exclude_label_selector VARCHAR;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists field_selector VARCHAR;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists include_name_pattern VARCHAR;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists exclude_name_pattern VARCHAR;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists exclude_containers text[];
+ALTER TABLE kube.namespaces ADD COLUMN if not exists discovered boolean DEFAULT false;
+ALTER TABLE kube.clusters ADD COLUMN if not exists discovery_patterns text[];
+ALTER TABLE kube.clusters ADD COLUMN if not exists discovery_regexp VARCHAR;
+ALTER TABLE kube.clusters ADD COLUMN if not exists discovery_label_selector VARCHAR;
+ALTER TABLE kube.clusters ADD COLUMN if not exists auth_type VARCHAR(20) DEFAULT 'kubeconfig';
+ALTER TABLE kube.clusters ADD COLUMN if not exists server VARCHAR;
+ALTER TABLE kube.clusters ADD COLUMN if not exists token_file VARCHAR;
+ALTER TABLE kube.clusters ADD COLUMN if not exists ca_file VARCHAR;
+ALTER TABLE kube.clusters ADD COLUMN if not exists grep_rules jsonb;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists grep_rules jsonb;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists disable_events boolean DEFAULT false;
+ALTER TABLE kube.namespaces ADD COLUMN if not exists disable_cronjobs boolean DEFAULT false;
+
+CREATE TABLE if not exists kube.scan_statuses (
+    id serial PRIMARY KEY,
+    cluster_name VARCHAR(30),
+    namespace VARCHAR,
+    last_attempt_time timestamptz,
+    last_success_time timestamptz,
+    duration bigint,
+    pods_count int,
+    error VARCHAR,
+
+    FOREIGN KEY (namespace, cluster_name) REFERENCES kube.namespaces (name, cluster_name) ON DELETE CASCADE,
+    UNIQUE (namespace, cluster_name)
+);
+
+CREATE OR REPLACE FUNCTION kube.scan_status_json(ss kube.scan_statuses)
+RETURNS json
+LANGUAGE sql
+STABLE
+AS
+$$
+    SELECT CASE WHEN ss.id is null THEN null ELSE json_build_object(
+        'last_attempt_time', ss.last_attempt_time,
+        'last_success_time', ss.last_success_time,
+        'duration', ss.duration,
+        'pods_count', ss.pods_count,
+        'error', coalesce(ss.error, '')
+    ) END
+$$;
+
 CREATE OR REPLACE VIEW v_clusters AS
-    SELECT kc.name, kc.config_str, coalesce(array_agg(ns.name) filter (WHERE ns.name is not null), ARRAY[]::text[]) as namespaces
+    SELECT kc.name, coalesce(kc.config_str, '') as config_str,
+           coalesce(array_agg(ns.name) filter (WHERE ns.name is not null and not coalesce(ns.discovered, false)), ARRAY[]::text[]) as namespaces,
+           coalesce(kc.log_parser, '') as log_parser,
+           coalesce(json_object_agg(ns.name, kube.scan_status_json(ss)) filter (WHERE ss.id is not null), '{}'::json) as scan_statuses,
+           coalesce(kc.scan_interval, 0) as scan_interval, coalesce(kc.scan_cron, '') as scan_cron, coalesce(kc.scan_paused, false) as scan_paused,
+           coalesce(array_agg(ns.name) filter (WHERE ns.discovered), ARRAY[]::text[]) as discovered_namespaces,
+           coalesce(kc.discovery_patterns, ARRAY[]::text[]) as discovery_patterns, coalesce(kc.discovery_regexp, '') as discovery_regexp,
+           coalesce(kc.discovery_label_selector, '') as discovery_label_selector,
+           coalesce(kc.auth_type, 'kubeconfig') as auth_type, coalesce(kc.server, '') as server,
+           coalesce(kc.token_file, '') as token_file, coalesce(kc.ca_file, '') as ca_file,
+           coalesce(kc.grep_rules, '[]'::jsonb) as grep_rules
     FROM kube.clusters kc LEFT JOIN kube.namespaces ns ON kc.name = ns.cluster_name
-    GROUP BY kc.name, kc.config_str;
+        LEFT JOIN kube.scan_statuses ss ON ss.cluster_name = ns.cluster_name and ss.namespace = ns.name
+    GROUP BY kc.name, kc.config_str, kc.log_parser, kc.scan_interval, kc.scan_cron, kc.scan_paused,
+             kc.discovery_patterns, kc.discovery_regexp, kc.discovery_label_selector, kc.auth_type, kc.server, kc.token_file, kc.ca_file,
+             kc.grep_rules;
+
+CREATE OR REPLACE VIEW v_namespaces AS
+    SELECT ns.name, ns.cluster_name, coalesce(ns.log_parser, '') as log_parser,
+           kube.scan_status_json(ss) as scan_status,
+           coalesce(ns.scan_interval, 0) as scan_interval, coalesce(ns.scan_cron, '') as scan_cron, coalesce(ns.scan_paused, false) as scan_paused,
+           coalesce(ns.include_label_selector, '') as include_label_selector, coalesce(ns.exclude_label_selector, '') as exclude_label_selector,
+           coalesce(ns.field_selector, '') as field_selector, coalesce(ns.include_name_pattern, '') as include_name_pattern,
+           coalesce(ns.exclude_name_pattern, '') as exclude_name_pattern, coalesce(ns.exclude_containers, ARRAY[]::text[]) as exclude_containers,
+           coalesce(ns.discovered, false) as discovered, coalesce(ns.grep_rules, '[]'::jsonb) as grep_rules,
+           coalesce(ns.disable_events, false) as disable_events, coalesce(ns.disable_cronjobs, false) as disable_cronjobs
+    FROM kube.namespaces ns
+        LEFT JOIN kube.scan_statuses ss ON ss.cluster_name = ns.cluster_name and ss.namespace = ns.name;
+
+CREATE TABLE if not exists kube.scans (
+    id serial PRIMARY KEY,
+    cluster_name VARCHAR(30),
+    namespace VARCHAR,
+    scan_type VARCHAR(20),
+    scan_time timestamptz DEFAULT now(),
+    scans jsonb,
+
+    FOREIGN KEY (namespace, cluster_name) REFERENCES kube.namespaces (name, cluster_name) ON DELETE CASCADE
+);
+
+CREATE INDEX if not exists scans_lookup_idx ON kube.scans (cluster_name, namespace, scan_type, scan_time);
+ALTER TABLE kube.scans ADD COLUMN if not exists last_scan_time timestamptz;
+ALTER TABLE kube.scans ADD COLUMN if not exists scans_hash VARCHAR(64);
+
+CREATE TABLE if not exists kube.job_logs (
+    id serial PRIMARY KEY,
+    cluster_name VARCHAR(30),
+    namespace VARCHAR,
+    job_name VARCHAR,
+    log bytea,
+    size bigint,
+    original_size bigint,
+    truncated boolean DEFAULT false,
+    save_time timestamptz DEFAULT now(),
+
+    FOREIGN KEY (namespace, cluster_name) REFERENCES kube.namespaces (name, cluster_name) ON DELETE CASCADE,
+    UNIQUE (cluster_name, namespace, job_name)
+);
+
+CREATE TABLE if not exists kube.checkpoints (
+    id serial PRIMARY KEY,
+    cluster_name VARCHAR(30),
+    namespace VARCHAR,
+    pod_uid VARCHAR,
+    container VARCHAR,
+    kind VARCHAR(20),
+    container_id VARCHAR,
+    log_time timestamptz,
+    log_lines int,
+    state jsonb,
+    save_time timestamptz DEFAULT now(),
+
+    FOREIGN KEY (namespace, cluster_name) REFERENCES kube.namespaces (name, cluster_name) ON DELETE CASCADE,
+    UNIQUE (cluster_name, namespace, pod_uid, container, kind)
+);
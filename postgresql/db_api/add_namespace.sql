@@ -14,6 +14,14 @@ BEGIN
         RAISE SQLSTATE '80003' USING message = 'no such cluster';
     end if;
 
+    -- Discovered namespace is pinned, so it isn't deleted when it stops matching discovery rules
+    UPDATE kube.namespaces
+    SET discovered=false
+    WHERE name=p_name and cluster_name=p_cluster_name and discovered;
+    if FOUND then
+        RETURN;
+    end if;
+
     INSERT INTO kube.namespaces(name, cluster_name)
     VALUES (p_name, p_cluster_name);
 END
@@ -1,20 +1,24 @@
-CREATE OR REPLACE FUNCTION kube_api.create_cluster(p_name varchar, p_config_str varchar)
+DROP FUNCTION IF EXISTS kube_api.create_cluster(varchar, varchar);
+
+CREATE OR REPLACE FUNCTION kube_api.create_cluster(p_name varchar, p_config_str varchar, p_auth_type varchar,
+    p_server varchar, p_token_file varchar, p_ca_file varchar)
 RETURNS kube.v_clusters
 LANGUAGE plpgsql
 AS
 $$
 DECLARE
     r_cluster kube.v_clusters;
+    v_auth_type varchar := coalesce(nullif(p_auth_type, ''), 'kubeconfig');
 BEGIN
     if coalesce(p_name, '') = '' then
         RAISE SQLSTATE '80010' USING message = 'empty cluster_name provided';
     end if;
-    if coalesce(p_config_str, '') = '' then
+    if v_auth_type = 'kubeconfig' and coalesce(p_config_str, '') = '' then
         RAISE SQLSTATE '80011' USING message = 'empty config_str string provided';
     end if;
 
-    INSERT INTO kube.clusters(name, config_str)
-    VALUES (p_name, p_config_str);
+    INSERT INTO kube.clusters(name, config_str, auth_type, server, token_file, ca_file)
+    VALUES (p_name, nullif(p_config_str, ''), v_auth_type, nullif(p_server, ''), nullif(p_token_file, ''), nullif(p_ca_file, ''));
 
     SELECT * from kube.v_clusters
     WHERE name=p_name
@@ -23,4 +27,4 @@ BEGIN
 
     RETURN r_cluster;
 END
-$$;
\ No newline at end of file
+$$;
@@ -1,23 +1,32 @@
-CREATE OR REPLACE FUNCTION kube_api.edit_cluster(p_name varchar, p_config_str varchar)
+DROP FUNCTION IF EXISTS kube_api.edit_cluster(varchar, varchar);
+
+CREATE OR REPLACE FUNCTION kube_api.edit_cluster(p_name varchar, p_config_str varchar, p_auth_type varchar,
+    p_server varchar, p_token_file varchar, p_ca_file varchar)
 RETURNS kube.v_clusters
 LANGUAGE plpgsql
 AS
 $$
 DECLARE
     r_cluster kube.v_clusters;
+    v_auth_type varchar := coalesce(nullif(p_auth_type, ''), 'kubeconfig');
 BEGIN
     if coalesce(p_name, '') = '' then
         RAISE SQLSTATE '80010' USING message = 'empty cluster_name provided';
     end if;
-    if coalesce(p_config_str, '') = '' then
+    if v_auth_type = 'kubeconfig' and coalesce(p_config_str, '') = '' then
         RAISE SQLSTATE '80011' USING message = 'empty config string provided';
     end if;
     IF NOT EXISTS (SELECT id from kube.clusters where name=p_name) then
         RAISE SQLSTATE '80012' USING message = 'no such cluster';
     end if;
 
+    -- Kubeconfig isn't kept when cluster is switched to other auth type
     UPDATE kube.clusters
-    SET config_str=p_config_str
+    SET config_str=nullif(p_config_str, ''),
+        auth_type=v_auth_type,
+        server=nullif(p_server, ''),
+        token_file=nullif(p_token_file, ''),
+        ca_file=nullif(p_ca_file, '')
     WHERE name=p_name;
 
     SELECT * from kube.v_clusters
@@ -27,4 +36,4 @@ BEGIN
 
     RETURN r_cluster;
 END
-$$;
\ No newline at end of file
+$$;
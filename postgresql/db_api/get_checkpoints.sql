@@ -0,0 +1,10 @@
+CREATE OR REPLACE FUNCTION kube_api.get_checkpoints(p_cluster_name varchar, p_namespace varchar)
+    RETURNS SETOF kube.checkpoints
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    RETURN QUERY select * from kube.checkpoints
+    where cluster_name=p_cluster_name and namespace=p_namespace;
+END
+$$;
@@ -0,0 +1,15 @@
+CREATE OR REPLACE FUNCTION kube_api.get_job_log(p_cluster_name varchar, p_namespace varchar, p_job_name varchar)
+RETURNS TABLE(job_name varchar, log bytea, size bigint, original_size bigint, truncated boolean, save_time timestamptz)
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    RETURN QUERY
+    SELECT jl.job_name, jl.log, jl.size, jl.original_size, jl.truncated, jl.save_time
+    FROM kube.job_logs jl
+    WHERE jl.cluster_name=p_cluster_name and jl.namespace=p_namespace and jl.job_name=p_job_name;
+    if not FOUND then
+        RAISE SQLSTATE '80005' USING message = 'no such job log';
+    end if;
+END
+$$;
@@ -0,0 +1,12 @@
+CREATE OR REPLACE FUNCTION kube_api.get_last_scans(p_cluster_name varchar, p_namespace varchar, p_scan_type varchar)
+    RETURNS SETOF kube.scans
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    RETURN QUERY select * from kube.scans
+    where cluster_name=p_cluster_name and namespace=p_namespace and scan_type=p_scan_type
+    order by scan_time desc
+    limit 1;
+END
+$$;
@@ -0,0 +1,17 @@
+CREATE OR REPLACE FUNCTION kube_api.get_namespace(p_cluster_name varchar, p_namespace varchar)
+RETURNS kube.v_namespaces
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_namespace kube.v_namespaces;
+    v_cnt int;
+BEGIN
+    select * INTO r_namespace from kube.v_namespaces where cluster_name=p_cluster_name and name=p_namespace limit 1;
+    GET DIAGNOSTICS v_cnt := ROW_COUNT;
+    if v_cnt = 0 then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+    RETURN r_namespace;
+END
+$$;
@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION kube_api.get_namespaces(p_cluster_name varchar)
+    RETURNS SETOF kube.v_namespaces
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    if not EXISTS(select id from kube.clusters where name=p_cluster_name) then
+        RAISE SQLSTATE '80003' USING message = 'no such cluster';
+    end if;
+
+    RETURN QUERY select * from kube.v_namespaces where cluster_name=p_cluster_name order by name;
+END
+$$;
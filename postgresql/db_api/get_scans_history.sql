@@ -0,0 +1,17 @@
+CREATE OR REPLACE FUNCTION kube_api.get_scans_history(p_cluster_name varchar, p_namespace varchar, p_scan_type varchar,
+                                                      p_from timestamptz, p_to timestamptz)
+    RETURNS SETOF kube.scans
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    if p_from > p_to then
+        RAISE SQLSTATE '80021' USING message = 'wrong time range provided';
+    end if;
+
+    RETURN QUERY select * from kube.scans
+    where cluster_name=p_cluster_name and namespace=p_namespace and scan_type=p_scan_type
+        and coalesce(last_scan_time, scan_time) >= p_from and scan_time <= p_to
+    order by scan_time;
+END
+$$;
@@ -0,0 +1,11 @@
+CREATE OR REPLACE FUNCTION kube_api.prune_job_logs(p_cluster_name varchar, p_namespace varchar, p_jobs_names text[])
+RETURNS void
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    DELETE FROM kube.job_logs
+    WHERE cluster_name=p_cluster_name and namespace=p_namespace
+        and not (job_name = ANY(coalesce(p_jobs_names, ARRAY[]::text[])));
+END
+$$;
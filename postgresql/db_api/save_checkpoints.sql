@@ -0,0 +1,29 @@
+-- p_checkpoints is json array of checkpoints, checkpoints of pods which are not in p_pods_uids are deleted
+CREATE OR REPLACE FUNCTION kube_api.save_checkpoints(p_cluster_name varchar, p_namespace varchar, p_checkpoints jsonb,
+    p_pods_uids text[])
+RETURNS void
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    if not EXISTS(select id from kube.namespaces where name=p_namespace and cluster_name=p_cluster_name) then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+
+    INSERT INTO kube.checkpoints(cluster_name, namespace, pod_uid, container, kind, container_id, log_time, log_lines, state)
+    SELECT p_cluster_name, p_namespace, cp.pod_uid, cp.container, cp.kind, cp.container_id, cp.log_time, cp.log_lines, cp.state
+    FROM jsonb_to_recordset(coalesce(p_checkpoints, '[]'::jsonb))
+        AS cp(pod_uid varchar, container varchar, kind varchar, container_id varchar, log_time timestamptz,
+              log_lines int, state jsonb)
+    ON CONFLICT (cluster_name, namespace, pod_uid, container, kind) DO UPDATE
+    SET container_id = excluded.container_id,
+        log_time = excluded.log_time,
+        log_lines = excluded.log_lines,
+        state = excluded.state,
+        save_time = now();
+
+    DELETE FROM kube.checkpoints
+    WHERE cluster_name=p_cluster_name and namespace=p_namespace
+        and not (pod_uid = ANY(coalesce(p_pods_uids, ARRAY[]::text[])));
+END
+$$;
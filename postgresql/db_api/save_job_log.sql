@@ -0,0 +1,21 @@
+CREATE OR REPLACE FUNCTION kube_api.save_job_log(p_cluster_name varchar, p_namespace varchar, p_job_name varchar, p_log bytea,
+    p_size bigint, p_original_size bigint, p_truncated boolean)
+RETURNS void
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    if not EXISTS(select id from kube.namespaces where name=p_namespace and cluster_name=p_cluster_name) then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+
+    INSERT INTO kube.job_logs(cluster_name, namespace, job_name, log, size, original_size, truncated)
+    VALUES (p_cluster_name, p_namespace, p_job_name, p_log, p_size, p_original_size, p_truncated)
+    ON CONFLICT (cluster_name, namespace, job_name) DO UPDATE
+    SET log = excluded.log,
+        size = excluded.size,
+        original_size = excluded.original_size,
+        truncated = excluded.truncated,
+        save_time = now();
+END
+$$;
@@ -0,0 +1,21 @@
+CREATE OR REPLACE FUNCTION kube_api.save_scan_status(p_cluster_name varchar, p_namespace varchar, p_last_attempt_time timestamptz,
+    p_last_success_time timestamptz, p_duration bigint, p_pods_count int, p_error varchar)
+RETURNS void
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    if not EXISTS(select id from kube.namespaces where name=p_namespace and cluster_name=p_cluster_name) then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+
+    INSERT INTO kube.scan_statuses(cluster_name, namespace, last_attempt_time, last_success_time, duration, pods_count, error)
+    VALUES (p_cluster_name, p_namespace, p_last_attempt_time, p_last_success_time, p_duration, p_pods_count, p_error)
+    ON CONFLICT (namespace, cluster_name) DO UPDATE
+    SET last_attempt_time = excluded.last_attempt_time,
+        last_success_time = coalesce(excluded.last_success_time, kube.scan_statuses.last_success_time),
+        duration = excluded.duration,
+        pods_count = excluded.pods_count,
+        error = excluded.error;
+END
+$$;
@@ -0,0 +1,48 @@
+DROP FUNCTION IF EXISTS kube_api.save_scans(varchar, varchar, varchar, jsonb);
+DROP FUNCTION IF EXISTS kube_api.save_scans(varchar, varchar, varchar, jsonb, integer);
+
+-- Scans with the same p_scans_hash as the last saved ones aren't saved again, last_scan_time of the saved ones is moved
+-- instead. Scans which weren't seen for p_retention_days are removed from history of namespace scan type,
+-- negative retention keeps them forever
+CREATE OR REPLACE FUNCTION kube_api.save_scans(p_cluster_name varchar, p_namespace varchar, p_scan_type varchar, p_scans jsonb,
+    p_scans_hash varchar, p_retention_days integer)
+RETURNS void
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_last_id int;
+    r_last_hash varchar;
+BEGIN
+    if coalesce(p_namespace, '') = '' then
+        RAISE SQLSTATE '80001' USING message = 'empty namespace provided';
+    end if;
+    if coalesce(p_cluster_name, '') = '' then
+        RAISE SQLSTATE '80002' USING message = 'empty cluster_name parameter provided';
+    end if;
+    if coalesce(p_scan_type, '') = '' then
+        RAISE SQLSTATE '80020' USING message = 'empty scan_type provided';
+    end if;
+    if not EXISTS(select id from kube.namespaces where name=p_namespace and cluster_name=p_cluster_name) then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+
+    SELECT id, scans_hash INTO r_last_id, r_last_hash FROM kube.scans
+    WHERE cluster_name=p_cluster_name and namespace=p_namespace and scan_type=p_scan_type
+    ORDER BY scan_time desc
+    LIMIT 1;
+
+    if r_last_id is not null and r_last_hash = p_scans_hash then
+        UPDATE kube.scans SET last_scan_time=now() WHERE id=r_last_id;
+    else
+        INSERT INTO kube.scans(cluster_name, namespace, scan_type, scans, scans_hash, last_scan_time)
+        VALUES (p_cluster_name, p_namespace, p_scan_type, coalesce(p_scans, '[]'::jsonb), p_scans_hash, now());
+    end if;
+
+    if p_retention_days >= 0 then
+        DELETE FROM kube.scans
+        WHERE cluster_name=p_cluster_name and namespace=p_namespace and scan_type=p_scan_type
+            and coalesce(last_scan_time, scan_time) < now() - make_interval(days => p_retention_days);
+    end if;
+END
+$$;
@@ -0,0 +1,30 @@
+CREATE OR REPLACE FUNCTION kube_api.set_cluster_discovery(p_name varchar, p_discovery_patterns text[], p_discovery_regexp varchar,
+    p_discovery_label_selector varchar)
+RETURNS kube.v_clusters
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_cluster kube.v_clusters;
+BEGIN
+    if coalesce(p_name, '') = '' then
+        RAISE SQLSTATE '80010' USING message = 'empty cluster_name provided';
+    end if;
+    IF NOT EXISTS (SELECT id from kube.clusters where name=p_name) then
+        RAISE SQLSTATE '80012' USING message = 'no such cluster';
+    end if;
+
+    UPDATE kube.clusters
+    SET discovery_patterns=nullif(p_discovery_patterns, ARRAY[]::text[]),
+        discovery_regexp=nullif(p_discovery_regexp, ''),
+        discovery_label_selector=nullif(p_discovery_label_selector, '')
+    WHERE name=p_name;
+
+    SELECT * from kube.v_clusters
+    WHERE name=p_name
+    limit 1
+    INTO r_cluster;
+
+    RETURN r_cluster;
+END
+$$;
@@ -0,0 +1,27 @@
+CREATE OR REPLACE FUNCTION kube_api.set_cluster_log_parser(p_name varchar, p_log_parser varchar)
+RETURNS kube.v_clusters
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_cluster kube.v_clusters;
+BEGIN
+    if coalesce(p_name, '') = '' then
+        RAISE SQLSTATE '80010' USING message = 'empty cluster_name provided';
+    end if;
+    IF NOT EXISTS (SELECT id from kube.clusters where name=p_name) then
+        RAISE SQLSTATE '80012' USING message = 'no such cluster';
+    end if;
+
+    UPDATE kube.clusters
+    SET log_parser=nullif(p_log_parser, '')
+    WHERE name=p_name;
+
+    SELECT * from kube.v_clusters
+    WHERE name=p_name
+    limit 1
+    INTO r_cluster;
+
+    RETURN r_cluster;
+END
+$$;
@@ -0,0 +1,29 @@
+CREATE OR REPLACE FUNCTION kube_api.set_cluster_schedule(p_name varchar, p_scan_interval int, p_scan_cron varchar, p_scan_paused boolean)
+RETURNS kube.v_clusters
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_cluster kube.v_clusters;
+BEGIN
+    if coalesce(p_name, '') = '' then
+        RAISE SQLSTATE '80010' USING message = 'empty cluster_name provided';
+    end if;
+    IF NOT EXISTS (SELECT id from kube.clusters where name=p_name) then
+        RAISE SQLSTATE '80012' USING message = 'no such cluster';
+    end if;
+
+    UPDATE kube.clusters
+    SET scan_interval=nullif(p_scan_interval, 0),
+        scan_cron=nullif(p_scan_cron, ''),
+        scan_paused=coalesce(p_scan_paused, false)
+    WHERE name=p_name;
+
+    SELECT * from kube.v_clusters
+    WHERE name=p_name
+    limit 1
+    INTO r_cluster;
+
+    RETURN r_cluster;
+END
+$$;
@@ -0,0 +1,31 @@
+CREATE OR REPLACE FUNCTION kube_api.set_namespace_filters(p_cluster_name varchar, p_namespace varchar,
+    p_include_label_selector varchar, p_exclude_label_selector varchar, p_field_selector varchar,
+    p_include_name_pattern varchar, p_exclude_name_pattern varchar, p_exclude_containers text[])
+RETURNS kube.v_namespaces
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_namespace kube.v_namespaces;
+BEGIN
+    if not EXISTS(select id from kube.namespaces where name=p_namespace and cluster_name=p_cluster_name) then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+
+    UPDATE kube.namespaces
+    SET include_label_selector=nullif(p_include_label_selector, ''),
+        exclude_label_selector=nullif(p_exclude_label_selector, ''),
+        field_selector=nullif(p_field_selector, ''),
+        include_name_pattern=nullif(p_include_name_pattern, ''),
+        exclude_name_pattern=nullif(p_exclude_name_pattern, ''),
+        exclude_containers=p_exclude_containers
+    WHERE name=p_namespace and cluster_name=p_cluster_name;
+
+    SELECT * from kube.v_namespaces
+    WHERE name=p_namespace and cluster_name=p_cluster_name
+    limit 1
+    INTO r_namespace;
+
+    RETURN r_namespace;
+END
+$$;
@@ -0,0 +1,24 @@
+CREATE OR REPLACE FUNCTION kube_api.set_namespace_grep_rules(p_cluster_name varchar, p_namespace varchar, p_grep_rules jsonb)
+RETURNS kube.v_namespaces
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_namespace kube.v_namespaces;
+BEGIN
+    if not EXISTS(select id from kube.namespaces where name=p_namespace and cluster_name=p_cluster_name) then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+
+    UPDATE kube.namespaces
+    SET grep_rules=p_grep_rules
+    WHERE name=p_namespace and cluster_name=p_cluster_name;
+
+    SELECT * from kube.v_namespaces
+    WHERE name=p_namespace and cluster_name=p_cluster_name
+    limit 1
+    INTO r_namespace;
+
+    RETURN r_namespace;
+END
+$$;
@@ -0,0 +1,27 @@
+CREATE OR REPLACE FUNCTION kube_api.set_namespace_schedule(p_cluster_name varchar, p_namespace varchar, p_scan_interval int,
+    p_scan_cron varchar, p_scan_paused boolean)
+RETURNS kube.v_namespaces
+LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    r_namespace kube.v_namespaces;
+BEGIN
+    if not EXISTS(select id from kube.namespaces where name=p_namespace and cluster_name=p_cluster_name) then
+        RAISE SQLSTATE '80004' USING message = 'no such namespace';
+    end if;
+
+    UPDATE kube.namespaces
+    SET scan_interval=nullif(p_scan_interval, 0),
+        scan_cron=nullif(p_scan_cron, ''),
+        scan_paused=coalesce(p_scan_paused, false)
+    WHERE name=p_namespace and cluster_name=p_cluster_name;
+
+    SELECT * from kube.v_namespaces
+    WHERE name=p_namespace and cluster_name=p_cluster_name
+    limit 1
+    INTO r_namespace;
+
+    RETURN r_namespace;
+END
+$$;
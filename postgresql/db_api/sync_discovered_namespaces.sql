@@ -0,0 +1,22 @@
+CREATE OR REPLACE FUNCTION kube_api.sync_discovered_namespaces(p_cluster_name varchar, p_namespaces text[])
+RETURNS void
+LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    if coalesce(p_cluster_name, '') = '' then
+        RAISE SQLSTATE '80002' USING message = 'empty cluster_name parameter provided';
+    end if;
+    if not EXISTS(select id from kube.clusters where name=p_cluster_name) then
+        RAISE SQLSTATE '80003' USING message = 'no such cluster';
+    end if;
+
+    -- Scans and statuses of disappeared namespaces are deleted by cascade
+    DELETE FROM kube.namespaces
+    WHERE cluster_name=p_cluster_name and discovered and not (name = ANY(coalesce(p_namespaces, ARRAY[]::text[])));
+
+    INSERT INTO kube.namespaces(name, cluster_name, discovered)
+    SELECT unnest(p_namespaces), p_cluster_name, true
+    ON CONFLICT (name, cluster_name) DO NOTHING;
+END
+$$;